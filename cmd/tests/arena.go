@@ -2,35 +2,45 @@ package main
 
 import (
 	"context"
-	"runtime"
+	"log"
 
 	"github.com/ChizhovVadim/CounterGo/internal/arena"
-	"github.com/ChizhovVadim/CounterGo/internal/evalbuilder"
-	"github.com/ChizhovVadim/CounterGo/pkg/engine"
 )
 
 func arenaHandler(args []string) error {
-	var tc = arena.TimeControl{
-		//FixedTime:  1 * time.Second,
-		FixedNodes: 2_000_000,
+	var settings, err = parseArenaConfig(args)
+	if err != nil {
+		return err
 	}
+	log.Printf("%+v\n", settings)
 
-	var gameConcurrency int
-	if tc.FixedNodes != 0 {
-		gameConcurrency = runtime.NumCPU()
-	} else {
-		gameConcurrency = runtime.NumCPU() / 2
+	tc, err := arena.ParseTimeControl(settings.TimeControl)
+	if err != nil {
+		return err
+	}
+	adjudication, err := withTablebase(settings.Adjudication)
+	if err != nil {
+		return err
 	}
 
-	return arena.Run(context.Background(), gameConcurrency, tc, newArenaEngine)
-}
+	var engineBuilder = func(experiment bool) (arena.IEngine, error) {
+		if experiment {
+			return buildEngine(settings.Experiment)
+		}
+		return buildEngine(settings.Base)
+	}
 
-func newArenaEngine(experiment bool) arena.IEngine {
-	//var options = engine.NewMainOptions(evalbuilder.Get(""))
-	var options = engine.NewBaseOptions(evalbuilder.Get(""))
-	options.Hash = 128
-	options.ExperimentSettings = experiment
-	var eng = engine.NewEngine(options)
-	eng.Prepare()
-	return eng
+	var config = arena.Config{
+		GameConcurrency: gameConcurrency(settings.Concurrency, tc),
+		TimeControl:     tc,
+		Adjudication:    adjudication,
+		Openings:        settings.Openings,
+		PgnPath:         mapPath(settings.PgnOut),
+		StatePath:       mapPath(settings.StatePath),
+	}
+	config.Openings.Path = mapPath(config.Openings.Path)
+	if settings.Sprt {
+		config.Sprt = &settings.SprtParams
+	}
+	return arena.Run(context.Background(), config, engineBuilder)
 }
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/ChizhovVadim/CounterGo/internal/arena"
+	"github.com/ChizhovVadim/CounterGo/internal/evalbuilder"
+	"github.com/ChizhovVadim/CounterGo/internal/tablebase"
+	"github.com/ChizhovVadim/CounterGo/pkg/engine"
+	"github.com/ChizhovVadim/CounterGo/pkg/uci"
+	"github.com/ChizhovVadim/CounterGo/pkg/uciclient"
+)
+
+// engineConfig describes an arena engine: built-in Counter or an external UCI binary.
+type engineConfig struct {
+	Name    string            `json:"name,omitempty"`
+	Path    string            `json:"path,omitempty"` // external UCI engine, built-in Counter if empty
+	Args    []string          `json:"args,omitempty"`
+	Eval    string            `json:"eval,omitempty"`   // evaluation of built-in engine
+	Preset  string            `json:"preset,omitempty"` // base or main search options of built-in engine
+	Options map[string]string `json:"options,omitempty"`
+}
+
+type arenaConfig struct {
+	TimeControl  string             `json:"tc"`
+	Concurrency  int                `json:"concurrency"` // 0 means by number of CPUs
+	Openings     arena.Openings     `json:"openings"`
+	PgnOut       string             `json:"pgnout"`
+	StatePath    string             `json:"state"` // journal of finished games, the arena resumes from it
+	Sprt         bool               `json:"sprt"`
+	SprtParams   arena.SprtConfig   `json:"sprtParams"`
+	Adjudication arena.Adjudication `json:"adjudication"`
+	Base         engineConfig       `json:"base"`
+	Experiment   engineConfig       `json:"experiment"`
+}
+
+func defaultArenaConfig() arenaConfig {
+	return arenaConfig{
+		TimeControl: "nodes=2000000",
+		Openings:    arena.Openings{Repeats: 1},
+		SprtParams:  arena.SprtConfig{Elo0: 0, Elo1: 5, Alpha: 0.05, Beta: 0.05},
+		Adjudication: arena.Adjudication{
+			ResignMoves:    3,
+			DrawScore:      10,
+			DrawMoveNumber: 40,
+		},
+		Base: engineConfig{Preset: "base"},
+		Experiment: engineConfig{Preset: "base",
+			Options: map[string]string{"ExperimentSettings": "true"}},
+	}
+}
+
+// parseArenaConfig reads the optional -config JSON file first, command line flags override it.
+func parseArenaConfig(args []string) (arenaConfig, error) {
+	var config = defaultArenaConfig()
+	var configPath string
+	newArenaFlagSet(&config, &configPath).Parse(args)
+	if configPath == "" {
+		return config, nil
+	}
+	config = defaultArenaConfig()
+	var err = loadJson(mapPath(configPath), &config)
+	if err != nil {
+		return arenaConfig{}, err
+	}
+	newArenaFlagSet(&config, &configPath).Parse(args)
+	return config, nil
+}
+
+func newArenaFlagSet(config *arenaConfig, configPath *string) *flag.FlagSet {
+	var flagset = flag.NewFlagSet("", flag.ExitOnError)
+	flagset.StringVar(configPath, "config", *configPath, "path to JSON arena config, flags override it")
+	flagset.StringVar(&config.TimeControl, "tc", config.TimeControl, "nodes=N, st=seconds or [moves/]base[+inc]")
+	flagset.IntVar(&config.Concurrency, "concurrency", config.Concurrency, "number of games played in parallel, 0 by number of CPUs")
+	flagset.StringVar(&config.PgnOut, "pgnout", config.PgnOut, "path to PGN file for finished games")
+	flagset.StringVar(&config.StatePath, "state", config.StatePath, "path to state file, the arena resumes from it")
+	flagset.BoolVar(&config.Sprt, "sprt", config.Sprt, "stop when SPRT of experiment engine is decided")
+	flagset.Float64Var(&config.SprtParams.Elo0, "elo0", config.SprtParams.Elo0, "SPRT H0 elo")
+	flagset.Float64Var(&config.SprtParams.Elo1, "elo1", config.SprtParams.Elo1, "SPRT H1 elo")
+	flagset.Float64Var(&config.SprtParams.Alpha, "alpha", config.SprtParams.Alpha, "SPRT alpha")
+	flagset.Float64Var(&config.SprtParams.Beta, "beta", config.SprtParams.Beta, "SPRT beta")
+	openingFlags(flagset, &config.Openings)
+	adjudicationFlags(flagset, &config.Adjudication)
+	engineFlags(flagset, "base", &config.Base)
+	engineFlags(flagset, "experiment", &config.Experiment)
+	return flagset
+}
+
+func openingFlags(flagset *flag.FlagSet, openings *arena.Openings) {
+	flagset.StringVar(&openings.Path, "openings", openings.Path, "EPD or PGN opening file, built-in openings if empty")
+	flagset.BoolVar(&openings.Random, "random", openings.Random, "play openings in random order")
+	flagset.Int64Var(&openings.Seed, "seed", openings.Seed, "seed of random opening order")
+	flagset.IntVar(&openings.Repeats, "repeats", openings.Repeats, "number of times each opening is played with both colours")
+	flagset.IntVar(&openings.Count, "openingcount", openings.Count, "play only the first openings of file, 0 plays all")
+}
+
+func adjudicationFlags(flagset *flag.FlagSet, adjudication *arena.Adjudication) {
+	flagset.IntVar(&adjudication.ResignScore, "resignscore", adjudication.ResignScore, "resign adjudication score in centipawns, 0 disables")
+	flagset.IntVar(&adjudication.ResignMoves, "resignmoves", adjudication.ResignMoves, "resign adjudication move count")
+	flagset.IntVar(&adjudication.DrawScore, "drawscore", adjudication.DrawScore, "draw adjudication score in centipawns")
+	flagset.IntVar(&adjudication.DrawMoveNumber, "drawmovenumber", adjudication.DrawMoveNumber, "draw adjudication starts from this move")
+	flagset.IntVar(&adjudication.DrawMoves, "drawmoves", adjudication.DrawMoves, "draw adjudication move count, 0 disables")
+	flagset.IntVar(&adjudication.MaxMoves, "maxmoves", adjudication.MaxMoves, "maximum game length in moves, 0 disables")
+	flagset.IntVar(&adjudication.TablebasePieces, "tbpieces", adjudication.TablebasePieces,
+		fmt.Sprintf("adjudicate positions with at most N pieces by built-in tablebase of up to %v pieces, 0 disables", tablebase.MaxPieces))
+}
+
+// withTablebase sets the built-in tablebase if tablebase adjudication is enabled.
+func withTablebase(adjudication arena.Adjudication) (arena.Adjudication, error) {
+	if adjudication.TablebasePieces == 0 {
+		return adjudication, nil
+	}
+	if adjudication.TablebasePieces > tablebase.MaxPieces {
+		return arena.Adjudication{}, fmt.Errorf("tablebase pieces %v, built-in tablebase has up to %v pieces",
+			adjudication.TablebasePieces, tablebase.MaxPieces)
+	}
+	adjudication.Tablebase = tablebase.New()
+	return adjudication, nil
+}
+
+func engineFlags(flagset *flag.FlagSet, prefix string, config *engineConfig) {
+	flagset.StringVar(&config.Name, prefix+"-name", config.Name, prefix+" engine name")
+	flagset.StringVar(&config.Path, prefix, config.Path, "path to external UCI engine used as "+prefix+" engine")
+	flagset.StringVar(&config.Eval, prefix+"-eval", config.Eval, prefix+" evaluation of built-in engine")
+	flagset.StringVar(&config.Preset, prefix+"-preset", config.Preset, prefix+" search options of built-in engine: base or main")
+	flagset.Var((*optionsFlag)(&config.Options), prefix+"-option", prefix+" engine option name=value, may be repeated")
+}
+
+// optionsFlag collects repeated name=value flags.
+type optionsFlag map[string]string
+
+func (f *optionsFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	var items []string
+	for name, value := range *f {
+		items = append(items, name+"="+value)
+	}
+	sort.Strings(items)
+	return strings.Join(items, ",")
+}
+
+func (f *optionsFlag) Set(s string) error {
+	var index = strings.Index(s, "=")
+	if index <= 0 {
+		return fmt.Errorf("option %q is not name=value", s)
+	}
+	if *f == nil {
+		*f = make(map[string]string)
+	}
+	(*f)[s[:index]] = s[index+1:]
+	return nil
+}
+
+func loadJson(path string, v interface{}) error {
+	var data, err = os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func gameConcurrency(concurrency int, tc arena.TimeControl) int {
+	if concurrency > 0 {
+		return concurrency
+	}
+	var result = runtime.NumCPU()
+	if tc.FixedNodes == 0 {
+		result /= 2
+	}
+	if result < 1 {
+		result = 1
+	}
+	return result
+}
+
+func buildEngine(config engineConfig) (arena.IEngine, error) {
+	var eng arena.IEngine
+	var err error
+	if config.Path != "" {
+		eng, err = newUciEngine(config)
+	} else {
+		eng, err = newBuiltinEngine(config)
+	}
+	if err != nil || config.Name == "" {
+		return eng, err
+	}
+	return &namedEngine{IEngine: eng, name: config.Name}, nil
+}
+
+// namedEngine replaces the engine name in PGN tags by the configured one.
+type namedEngine struct {
+	arena.IEngine
+	name string
+}
+
+func (e *namedEngine) Name() string {
+	return e.name
+}
+
+func (e *namedEngine) Err() error {
+	if failing, ok := e.IEngine.(interface{ Err() error }); ok {
+		return failing.Err()
+	}
+	return nil
+}
+
+func (e *namedEngine) Close() error {
+	if closer, ok := e.IEngine.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+func newBuiltinEngine(config engineConfig) (arena.IEngine, error) {
+	var options engine.Options
+	switch config.Preset {
+	case "", "base":
+		options = engine.NewBaseOptions(evalbuilder.Get(config.Eval))
+	case "main":
+		options = engine.NewMainOptions(evalbuilder.Get(config.Eval))
+	default:
+		return nil, fmt.Errorf("bad engine preset %v", config.Preset)
+	}
+	options.Hash = 128
+	var eng = engine.NewEngine(options)
+	var uciOptions = []uci.Option{
+		&uci.IntOption{Name: "Hash", Min: 4, Max: 1 << 16, Value: &eng.Options.Hash},
+		&uci.IntOption{Name: "Threads", Min: 1, Max: runtime.NumCPU(), Value: &eng.Options.Threads},
+		&uci.BoolOption{Name: "ExperimentSettings", Value: &eng.Options.ExperimentSettings},
+		&uci.BoolOption{Name: "AspirationWindows", Value: &eng.Options.AspirationWindows},
+		&uci.BoolOption{Name: "ReverseFutility", Value: &eng.Options.ReverseFutility},
+		&uci.BoolOption{Name: "NullMovePruning", Value: &eng.Options.NullMovePruning},
+		&uci.BoolOption{Name: "Probcut", Value: &eng.Options.Probcut},
+		&uci.BoolOption{Name: "CheckExt", Value: &eng.Options.CheckExt},
+		&uci.BoolOption{Name: "SingularExt", Value: &eng.Options.SingularExt},
+		&uci.BoolOption{Name: "Lmp", Value: &eng.Options.Lmp},
+		&uci.BoolOption{Name: "Futility", Value: &eng.Options.Futility},
+		&uci.BoolOption{Name: "See", Value: &eng.Options.See},
+	}
+	for name, value := range config.Options {
+		var option = findUciOption(uciOptions, name)
+		if option == nil {
+			return nil, fmt.Errorf("unknown engine option %v", name)
+		}
+		var err = option.Set(value)
+		if err != nil {
+			return nil, fmt.Errorf("engine option %v: %w", name, err)
+		}
+	}
+	eng.Prepare()
+	return eng, nil
+}
+
+func findUciOption(options []uci.Option, name string) uci.Option {
+	for _, option := range options {
+		if strings.EqualFold(option.UciName(), name) {
+			return option
+		}
+	}
+	return nil
+}
+
+func newUciEngine(config engineConfig) (arena.IEngine, error) {
+	var options = []uciclient.Option{{Name: "Hash", Value: "128"}}
+	var names = make([]string, 0, len(config.Options))
+	for name := range config.Options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if strings.EqualFold(name, "Hash") {
+			options[0].Value = config.Options[name]
+			continue
+		}
+		options = append(options, uciclient.Option{Name: name, Value: config.Options[name]})
+	}
+	var eng = uciclient.NewEngine(mapPath(config.Path), config.Args, options)
+	var err = eng.Start()
+	if err != nil {
+		return nil, err
+	}
+	return eng, nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"time"
+
+	"github.com/ChizhovVadim/CounterGo/pkg/common"
+	"github.com/ChizhovVadim/CounterGo/pkg/engine"
+)
+
+var benchFens = []string{
+	common.InitialPositionFen,
+	"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq -",
+	"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - -",
+	"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+	"rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8",
+	"r4rk1/1pp1qppp/p1np1n2/2b1p1B1/2B1P1b1/P1NP1N2/1PP1QPPP/R4RK1 w - - 0 10",
+	"r1bqkb1r/pp3ppp/2n1pn2/2pp4/3P4/2PBPN2/PP1N1PPP/R1BQK2R b KQkq - 0 6",
+	"2r2rk1/pp1bqppp/2n1pn2/3p4/2PP4/P1N1PN2/1P2BPPP/R2Q1RK1 w - - 1 12",
+	"r2q1rk1/1b2bppp/p2ppn2/1p6/3NP3/1BN1BP2/PPPQ2PP/2KR3R w - - 0 13",
+	"6k1/5pp1/4p2p/3pP3/1r1P4/5PP1/5K1P/R7 w - - 0 35",
+	"8/8/4kp2/2p3p1/2P3P1/4KP2/8/8 w - - 0 50",
+	"3r2k1/5ppp/8/8/8/2B5/5PPP/6K1 b - - 0 40",
+}
+
+// benchHandler searches fixed positions to fixed depth and reports nodes per second.
+// Search is deterministic, so the fastest of repeats is the least disturbed by other processes.
+func benchHandler(args []string) error {
+	var (
+		evalName = ""
+		depth    = 10
+		repeat   = 1
+	)
+
+	var flagset = flag.NewFlagSet("", flag.ExitOnError)
+	flagset.StringVar(&evalName, "eval", evalName, "evaluation function")
+	flagset.IntVar(&depth, "depth", depth, "search depth")
+	flagset.IntVar(&repeat, "repeat", repeat, "number of runs, the fastest is reported")
+	flagset.Parse(args)
+
+	var eng = newEngine(evalName)
+	eng.Options.Hash = 16
+	eng.Prepare()
+
+	var nodes int64
+	var elapsed time.Duration
+	for i := 0; i < repeat; i++ {
+		var runNodes, runElapsed, err = benchRun(eng, depth)
+		if err != nil {
+			return err
+		}
+		if i == 0 || runElapsed < elapsed {
+			nodes, elapsed = runNodes, runElapsed
+		}
+	}
+	log.Println("bench",
+		"eval", evalName,
+		"depth", depth,
+		"nodes", nodes,
+		"time", elapsed,
+		"nps", int(float64(nodes)/elapsed.Seconds()))
+	return nil
+}
+
+func benchRun(eng *engine.Engine, depth int) (int64, time.Duration, error) {
+	var nodes int64
+	var elapsed time.Duration
+	for _, fen := range benchFens {
+		var p, err = common.NewPositionFromFEN(fen)
+		if err != nil {
+			return 0, 0, err
+		}
+		eng.Clear()
+		var start = time.Now()
+		var si = eng.Search(context.Background(), common.SearchParams{
+			Positions: []common.Position{p},
+			Limits:    common.LimitsType{Depth: depth},
+		})
+		elapsed += time.Since(start)
+		nodes += si.Nodes
+	}
+	return nodes, elapsed, nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	nnue "github.com/ChizhovVadim/CounterGo/pkg/eval/nnue"
+)
+
+// convertNetHandler converts legacy and headerless trainer files to network file format.
+func convertNetHandler(args []string) error {
+	var (
+		inputPath   = ""
+		outputPath  = ""
+		raw         = false
+		arch        = "absolute"
+		hiddenSize  = 512
+		buckets     = 1
+		outputScale = 0.0
+		quantize    = true
+	)
+
+	var flagset = flag.NewFlagSet("", flag.ExitOnError)
+	flagset.StringVar(&inputPath, "input", inputPath, "path to network file")
+	flagset.StringVar(&outputPath, "output", outputPath, "path to converted network file")
+	flagset.BoolVar(&raw, "raw", raw, "input is float32 weights without header, topology is given by -arch and -hidden")
+	flagset.StringVar(&arch, "arch", arch, "architecture of raw input: absolute, perspective or kingbuckets")
+	flagset.IntVar(&hiddenSize, "hidden", hiddenSize, "hidden layer size of raw input")
+	flagset.IntVar(&buckets, "outputbuckets", buckets, "number of outputs of raw input")
+	flagset.Float64Var(&outputScale, "outputscale", outputScale, "centipawns per unit of network output, 0 keeps scale of input, 1 for raw input")
+	flagset.BoolVar(&quantize, "quantize", quantize, "store quantization scales in network file")
+	flagset.Parse(args)
+
+	if inputPath == "" || outputPath == "" {
+		return fmt.Errorf("input and output paths are required")
+	}
+
+	var net *nnue.Net
+	if raw {
+		netArch, layout, err := parseArchitecture(arch)
+		if err != nil {
+			return err
+		}
+		var header = nnue.NetHeader{
+			Arch:        netArch,
+			Layout:      layout,
+			InputSize:   nnue.InputSize,
+			LayerSizes:  []int{hiddenSize, buckets},
+			Activations: []nnue.Activation{nnue.ActReLU},
+			OutputScale: 1,
+		}
+		if netArch == nnue.ArchPerspective {
+			header.InputSize = layout.InputSize()
+		}
+		f, err := os.Open(mapPath(inputPath))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		net, err = nnue.ReadRawNet(f, header)
+		if err != nil {
+			return err
+		}
+	} else {
+		var err error
+		net, err = nnue.LoadNetFile(mapPath(inputPath))
+		if err != nil {
+			return err
+		}
+	}
+	if outputScale != 0 {
+		net.OutputScale = float32(outputScale)
+		net.HiddenScale, net.QuantOutputScale = 0, 0
+	}
+	if quantize {
+		var err = net.PinQuantScales()
+		if err != nil {
+			return err
+		}
+	}
+	log.Println("network", net.NetHeader.String(),
+		"outputScale", net.OutputScale,
+		"hiddenScale", net.HiddenScale,
+		"quantOutputScale", net.QuantOutputScale)
+	return nnue.SaveNetFile(mapPath(outputPath), net)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"runtime"
+
+	"github.com/ChizhovVadim/CounterGo/internal/datagen"
+	"github.com/ChizhovVadim/CounterGo/internal/dataset"
+	"github.com/ChizhovVadim/CounterGo/internal/evalbuilder"
+	"github.com/ChizhovVadim/CounterGo/pkg/engine"
+)
+
+func datagenHandler(args []string) error {
+	var (
+		evalName = ""
+		hash     = 16
+		config   = datagen.Config{
+			Games:       10_000,
+			Nodes:       5_000,
+			Concurrency: runtime.NumCPU(),
+			RandomPlies: 8,
+			ResignScore: 2000,
+			ResignPlies: 8,
+			MaxPlies:    400,
+		}
+	)
+
+	var flagset = flag.NewFlagSet("", flag.ExitOnError)
+	flagset.StringVar(&config.OutputPath, "output", config.OutputPath, "path to output file, binary dataset if extension is .bin, PGN otherwise")
+	flagset.IntVar(&config.Games, "games", config.Games, "number of games")
+	flagset.IntVar(&config.Nodes, "nodes", config.Nodes, "nodes per move")
+	flagset.IntVar(&config.Concurrency, "concurrency", config.Concurrency, "number of games played in parallel")
+	flagset.Int64Var(&config.Seed, "seed", config.Seed, "seed of random openings")
+	flagset.StringVar(&config.BookPath, "book", config.BookPath, "EPD or PGN opening file, start position if empty")
+	flagset.IntVar(&config.RandomPlies, "randomplies", config.RandomPlies, "random moves after book position")
+	flagset.IntVar(&config.ResignScore, "resignscore", config.ResignScore, "resign adjudication score in centipawns, 0 disables")
+	flagset.IntVar(&config.ResignPlies, "resignplies", config.ResignPlies, "resign adjudication ply count")
+	flagset.IntVar(&config.MaxPlies, "maxplies", config.MaxPlies, "game is drawn after this number of plies, 0 disables")
+	flagset.StringVar(&evalName, "eval", evalName, "evaluation function")
+	flagset.IntVar(&hash, "hash", hash, "hash size in MB per engine")
+	flagset.Parse(args)
+
+	if config.OutputPath == "" {
+		return fmt.Errorf("output path is required")
+	}
+	config.OutputPath = mapPath(config.OutputPath)
+	config.Binary = dataset.IsPackedDataset(config.OutputPath)
+	config.BookPath = mapPath(config.BookPath)
+	if config.Concurrency < 1 {
+		config.Concurrency = 1
+	}
+
+	return datagen.Run(context.Background(), config, func() datagen.IEngine {
+		var options = engine.NewMainOptions(evalbuilder.Get(evalName))
+		options.Hash = hash
+		var eng = engine.NewEngine(options)
+		eng.Prepare()
+		return eng
+	})
+}
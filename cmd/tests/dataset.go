@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"runtime"
+
+	"github.com/ChizhovVadim/CounterGo/internal/dataset"
+)
+
+// packHandler converts a folder of annotated PGN files to a binary dataset file.
+func packHandler(args []string) error {
+	var (
+		gamesFolderPath = ""
+		outputPath      = ""
+		concurrency     = runtime.NumCPU()
+		filterConfig    = dataset.DefaultFilterConfig()
+	)
+	var flagset = flag.NewFlagSet("", flag.ExitOnError)
+	flagset.StringVar(&gamesFolderPath, "input", gamesFolderPath, "folder with PGN files")
+	flagset.StringVar(&outputPath, "output", outputPath, "path to binary dataset file")
+	flagset.IntVar(&concurrency, "concurrency", concurrency, "number of parsing goroutines")
+	filterFlags(flagset, &filterConfig)
+	flagset.Parse(args)
+
+	if gamesFolderPath == "" || outputPath == "" {
+		return fmt.Errorf("input and output are required")
+	}
+	return dataset.ConvertPgnToPacked(context.Background(),
+		mapPath(gamesFolderPath), mapPath(outputPath), dataset.NewFilter(filterConfig), concurrency)
+}
+
+func shuffleHandler(args []string) error {
+	var (
+		inputPath  = ""
+		outputPath = ""
+		bucketSize = 10_000_000
+		seed       int64
+	)
+	var flagset = flag.NewFlagSet("", flag.ExitOnError)
+	flagset.StringVar(&inputPath, "input", inputPath, "path to binary dataset file")
+	flagset.StringVar(&outputPath, "output", outputPath, "path to shuffled binary dataset file")
+	flagset.IntVar(&bucketSize, "bucket", bucketSize, "positions shuffled in memory at once")
+	flagset.Int64Var(&seed, "seed", seed, "shuffle seed")
+	flagset.Parse(args)
+
+	if inputPath == "" || outputPath == "" {
+		return fmt.Errorf("input and output are required")
+	}
+	return dataset.ShufflePackedFile(mapPath(inputPath), mapPath(outputPath), bucketSize, seed)
+}
+
+func filterFlags(flagset *flag.FlagSet, config *dataset.FilterConfig) {
+	flagset.IntVar(&config.MinPly, "minply", config.MinPly, "skip positions before this game ply")
+	flagset.IntVar(&config.MaxPly, "maxply", config.MaxPly, "skip positions after this game ply, 0 disables")
+	flagset.IntVar(&config.MaxScore, "maxscore", config.MaxScore, "skip positions with larger absolute score in centipawns, 0 disables")
+	flagset.BoolVar(&config.SkipMate, "skipmate", config.SkipMate, "skip positions with mate scores")
+	flagset.BoolVar(&config.SkipCheck, "skipcheck", config.SkipCheck, "skip positions in check")
+	flagset.BoolVar(&config.SkipCapture, "skipcapture", config.SkipCapture, "skip positions where the best move is a capture")
+	flagset.BoolVar(&config.QuietSee, "quietsee", config.QuietSee, "skip positions with a winning capture by SEE")
+	flagset.IntVar(&config.MinPieces, "minpieces", config.MinPieces, "skip positions with fewer pieces")
+	flagset.IntVar(&config.MaxPieces, "maxpieces", config.MaxPieces, "skip positions with more pieces, 0 disables")
+	flagset.IntVar(&config.MaxPerPieceCount, "maxperpiececount", config.MaxPerPieceCount, "maximum positions per piece count, 0 disables")
+	flagset.BoolVar(&config.Dedup, "dedup", config.Dedup, "skip positions seen before in any file, a packed dataset is deduplicated when packed and training on it skips validation positions")
+}
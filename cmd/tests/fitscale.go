@@ -0,0 +1,36 @@
+package main
+
+import (
+	"flag"
+	"log"
+
+	"github.com/ChizhovVadim/CounterGo/internal/evalbuilder"
+	"github.com/ChizhovVadim/CounterGo/internal/quality"
+)
+
+const qualityDatasetPath = "~/chess/tuner/quiet-labeled.epd"
+
+func fitScaleHandler(args []string) error {
+	var (
+		evalName       = ""
+		valDatasetPath = qualityDatasetPath
+	)
+
+	var flagset = flag.NewFlagSet("", flag.ExitOnError)
+	flagset.StringVar(&evalName, "eval", evalName, "evaluation function")
+	flagset.StringVar(&valDatasetPath, "input", valDatasetPath, "path to labeled EPD file")
+	flagset.Parse(args)
+
+	var _, err = fitSigmoidScale(evalName, valDatasetPath)
+	return err
+}
+
+// fitSigmoidScale finds sigmoid scale of evaluation scores for tuner and trainer targets.
+func fitSigmoidScale(evalName, valDatasetPath string) (float64, error) {
+	log.Println("fit sigmoid scale started",
+		"evalName", evalName)
+	defer log.Println("fit sigmoid scale finished")
+
+	var eval = evalbuilder.Get(evalName)().(quality.ICentipawnEvaluator)
+	return quality.FitSigmoidScale(eval, mapPath(valDatasetPath))
+}
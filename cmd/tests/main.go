@@ -30,12 +30,28 @@ func run(args []string) error {
 		return tacticHandler(args)
 	case "arena":
 		return arenaHandler(args)
+	case "tournament":
+		return tournamentHandler(args)
+	case "datagen":
+		return datagenHandler(args)
+	case "pack":
+		return packHandler(args)
+	case "shuffle":
+		return shuffleHandler(args)
+	case "rescore":
+		return rescoreHandler(args)
+	case "fitscale":
+		return fitScaleHandler(args)
 	case "tuner":
 		return tunerHandler(args)
 	case "train":
 		return trainHandler(args)
+	case "convertnet":
+		return convertNetHandler(args)
 	case "perft":
 		return perftHandler(args)
+	case "bench":
+		return benchHandler(args)
 	case "play":
 		return utils.PlayCli(newEngine(""))
 	default:
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"github.com/ChizhovVadim/CounterGo/internal/arena"
+	"github.com/ChizhovVadim/CounterGo/internal/evalbuilder"
+	"github.com/ChizhovVadim/CounterGo/internal/quality"
+	"github.com/ChizhovVadim/CounterGo/internal/tactic"
+	"github.com/ChizhovVadim/CounterGo/pkg/common"
+)
+
+// netReportConfig selects checks of every network saved by train command, empty paths and zero nodes disable them.
+// Checks of missing files are skipped.
+type netReportConfig struct {
+	Quality       string `json:"quality"` // labeled EPD positions of quality command
+	Tactic        string `json:"tactic"`  // EPD suite of tactic command
+	TacticNodes   int    `json:"tacticNodes"`
+	MatchNodes    int    `json:"matchNodes"` // fixed-node match against the previous best network
+	MatchOpenings int    `json:"matchOpenings"`
+}
+
+type netReport struct {
+	QualityMse *float64     `json:"qualityMse,omitempty"`
+	Tactic     *tacticScore `json:"tactic,omitempty"`
+	Match      *matchScore  `json:"match,omitempty"`
+}
+
+type tacticScore struct {
+	Solved int `json:"solved"`
+	Total  int `json:"total"`
+	Nodes  int `json:"nodes"`
+}
+
+type matchScore struct {
+	Opponent string  `json:"opponent"`
+	Wins     int     `json:"wins"`
+	Losses   int     `json:"losses"`
+	Draws    int     `json:"draws"`
+	Score    float64 `json:"score"`
+	Best     bool    `json:"best"` // network scored more than half and replaced opponent as the best one
+}
+
+// newNetEvaluator returns evaluation of train.Config, nil if every check is disabled.
+// The first network is the best one, later networks replace it by winning the match.
+func newNetEvaluator(config netReportConfig, concurrency int) (func(netPath string) (interface{}, error), error) {
+	var err error
+	config.Quality, err = existingReportFile("quality", config.Quality)
+	if err != nil {
+		return nil, err
+	}
+	config.Tactic, err = existingReportFile("tactic", config.Tactic)
+	if err != nil {
+		return nil, err
+	}
+	var tests []tactic.EpdItem
+	if config.Tactic != "" && config.TacticNodes > 0 {
+		tests, err = tactic.LoadEpd(config.Tactic)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if config.Quality == "" && len(tests) == 0 && config.MatchNodes <= 0 {
+		return nil, nil
+	}
+	var bestNetPath string
+	return func(netPath string) (interface{}, error) {
+		var report netReport
+		if config.Quality != "" {
+			var eval = evalbuilder.Get(netPath)().(quality.IEvaluator)
+			var cost, err = quality.Cost(eval, config.Quality)
+			if err != nil {
+				return nil, err
+			}
+			report.QualityMse = &cost
+			log.Println("quality mse", cost)
+		}
+		if len(tests) != 0 {
+			var eng = newEngine(netPath)
+			eng.Options.ProgressMinNodes = 0
+			eng.Prepare()
+			report.Tactic = &tacticScore{
+				Solved: tactic.CountSolved(tests, eng, common.LimitsType{Nodes: config.TacticNodes}),
+				Total:  len(tests),
+				Nodes:  config.TacticNodes,
+			}
+			log.Println("tactic solved", report.Tactic.Solved, "total", report.Tactic.Total)
+		}
+		if config.MatchNodes > 0 && bestNetPath != "" {
+			var match, err = runNetMatch(netPath, bestNetPath, config, concurrency)
+			if err != nil {
+				return nil, err
+			}
+			report.Match = match
+		}
+		if bestNetPath == "" || report.Match != nil && report.Match.Best {
+			bestNetPath = netPath
+		}
+		return report, nil
+	}, nil
+}
+
+// existingReportFile returns path of check, empty if file is missing.
+func existingReportFile(check, path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	var _, err = os.Stat(path)
+	if os.IsNotExist(err) {
+		log.Println(check, "check skipped, file not found", path)
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+func runNetMatch(netPath, opponentPath string, config netReportConfig, concurrency int) (*matchScore, error) {
+	var tc = arena.TimeControl{FixedNodes: config.MatchNodes}
+	var settings = defaultArenaConfig()
+	settings.Openings.Count = config.MatchOpenings
+	var result, err = arena.RunMatch(context.Background(), arena.Config{
+		GameConcurrency: gameConcurrency(concurrency, tc),
+		TimeControl:     tc,
+		Adjudication:    settings.Adjudication,
+		Openings:        settings.Openings,
+	}, func(experiment bool) (arena.IEngine, error) {
+		if experiment {
+			return buildEngine(engineConfig{Eval: netPath})
+		}
+		return buildEngine(engineConfig{Eval: opponentPath})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &matchScore{
+		Opponent: opponentPath,
+		Wins:     result.Wins,
+		Losses:   result.Losses,
+		Draws:    result.Draws,
+		Score:    result.Score(),
+		Best:     result.Score() > 0.5,
+	}, nil
+}
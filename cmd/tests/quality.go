@@ -11,7 +11,7 @@ import (
 func qualityHandler(args []string) error {
 	var (
 		evalName       = ""
-		valDatasetPath = mapPath("~/chess/tuner/quiet-labeled.epd")
+		valDatasetPath = mapPath(qualityDatasetPath)
 	)
 
 	var flagset = flag.NewFlagSet("", flag.ExitOnError)
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"runtime"
+
+	"github.com/ChizhovVadim/CounterGo/internal/datagen"
+	"github.com/ChizhovVadim/CounterGo/internal/evalbuilder"
+	"github.com/ChizhovVadim/CounterGo/pkg/engine"
+)
+
+func rescoreHandler(args []string) error {
+	var (
+		evalName = ""
+		hash     = 16
+		config   = datagen.RescoreConfig{
+			Concurrency: runtime.NumCPU(),
+		}
+	)
+
+	var flagset = flag.NewFlagSet("", flag.ExitOnError)
+	flagset.StringVar(&config.InputPath, "input", config.InputPath, "path to binary dataset file")
+	flagset.StringVar(&config.OutputPath, "output", config.OutputPath, "path to rescored binary dataset file")
+	flagset.IntVar(&config.Depth, "depth", config.Depth, "search depth")
+	flagset.IntVar(&config.Nodes, "nodes", config.Nodes, "search nodes")
+	flagset.IntVar(&config.Concurrency, "concurrency", config.Concurrency, "number of parallel searches")
+	flagset.StringVar(&evalName, "eval", evalName, "evaluation function")
+	flagset.IntVar(&hash, "hash", hash, "hash size in MB per engine")
+	flagset.Parse(args)
+
+	if config.InputPath == "" || config.OutputPath == "" {
+		return fmt.Errorf("input and output are required")
+	}
+	config.InputPath = mapPath(config.InputPath)
+	config.OutputPath = mapPath(config.OutputPath)
+	if config.Concurrency < 1 {
+		config.Concurrency = 1
+	}
+
+	return datagen.Rescore(context.Background(), config, func() datagen.IEngine {
+		var options = engine.NewMainOptions(evalbuilder.Get(evalName))
+		options.Hash = hash
+		var eng = engine.NewEngine(options)
+		eng.Prepare()
+		return eng
+	})
+}
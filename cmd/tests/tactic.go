@@ -10,9 +10,11 @@ import (
 	"github.com/ChizhovVadim/CounterGo/pkg/engine"
 )
 
+const tacticDatasetPath = "~/chess/tests/tests.epd"
+
 func tacticHandler(args []string) error {
 	var (
-		filepath = mapPath("~/chess/tests/tests.epd")
+		filepath = mapPath(tacticDatasetPath)
 		evalName = ""
 		moveTime = 3 * time.Second
 	)
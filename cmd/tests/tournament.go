@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/ChizhovVadim/CounterGo/internal/arena"
+)
+
+// Players are given as arguments: "base", "main", "experiment" for in-process engines,
+// a JSON engine config file or a path to an external UCI engine.
+func tournamentHandler(args []string) error {
+	var (
+		timeControl  = "nodes=2000000"
+		concurrency  = 0
+		gauntlet     = false
+		pgnPath      = ""
+		statePath    = ""
+		openings     = arena.Openings{Repeats: 1}
+		adjudication = defaultArenaConfig().Adjudication
+	)
+
+	var flagset = flag.NewFlagSet("", flag.ExitOnError)
+	flagset.StringVar(&timeControl, "tc", timeControl, "nodes=N, st=seconds or [moves/]base[+inc]")
+	flagset.IntVar(&concurrency, "concurrency", concurrency, "number of games played in parallel, 0 by number of CPUs")
+	flagset.BoolVar(&gauntlet, "gauntlet", gauntlet, "first player plays all others instead of round robin")
+	flagset.StringVar(&pgnPath, "pgnout", pgnPath, "path to PGN file for finished games")
+	flagset.StringVar(&statePath, "state", statePath, "path to state file, the tournament resumes from it")
+	openingFlags(flagset, &openings)
+	adjudicationFlags(flagset, &adjudication)
+	flagset.Parse(args)
+
+	tc, err := arena.ParseTimeControl(timeControl)
+	if err != nil {
+		return err
+	}
+	adjudication, err = withTablebase(adjudication)
+	if err != nil {
+		return err
+	}
+
+	var players []arena.Player
+	var names = make(map[string]int)
+	for _, spec := range flagset.Args() {
+		player, err := newPlayer(spec)
+		if err != nil {
+			return err
+		}
+		names[player.Name]++
+		if names[player.Name] > 1 {
+			player.Name = fmt.Sprintf("%v-%v", player.Name, names[player.Name])
+		}
+		players = append(players, player)
+	}
+
+	openings.Path = mapPath(openings.Path)
+	return arena.RunTournament(context.Background(), arena.TournamentConfig{
+		GameConcurrency: gameConcurrency(concurrency, tc),
+		TimeControl:     tc,
+		Adjudication:    adjudication,
+		Openings:        openings,
+		Gauntlet:        gauntlet,
+		PgnPath:         mapPath(pgnPath),
+		StatePath:       mapPath(statePath),
+	}, players)
+}
+
+func newPlayer(spec string) (arena.Player, error) {
+	var config engineConfig
+	switch {
+	case spec == "base":
+		config = defaultArenaConfig().Base
+	case spec == "experiment":
+		config = defaultArenaConfig().Experiment
+	case spec == "main":
+		config = engineConfig{Preset: "main"}
+	case strings.HasSuffix(spec, ".json"):
+		var err = loadJson(mapPath(spec), &config)
+		if err != nil {
+			return arena.Player{}, err
+		}
+	default:
+		config = engineConfig{Path: spec}
+	}
+	var name = config.Name
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(spec), ".json")
+	}
+	return arena.Player{
+		Name: name,
+		Build: func() (arena.IEngine, error) {
+			return buildEngine(config)
+		},
+	}, nil
+}
@@ -1,42 +1,144 @@
 package main
 
 import (
+	"fmt"
 	"log"
 	"math/rand"
-	"runtime"
+	"strconv"
+	"strings"
 
+	"github.com/ChizhovVadim/CounterGo/internal/dataset"
 	"github.com/ChizhovVadim/CounterGo/internal/ml"
 	"github.com/ChizhovVadim/CounterGo/internal/train"
+	nnue "github.com/ChizhovVadim/CounterGo/pkg/eval/nnue"
 )
 
 func trainHandler(args []string) error {
-	var (
-		gamesFolderPath = mapPath("~/chess/Dataset2023")
-		netFolderPath   = mapPath("~/chess/net")
-		sigmoidScale    = 0.011
-		searchRatio     = 1.0
-		maxDatasetSize  = 50_000_000
-		epochs          = 15
-		concurrency     = runtime.NumCPU()
-		mirrorPos       = true
-		costName        = "mse"
-		hiddenSize      = 512
-	)
-	var buildFeatureService = func() train.IFeatureProvider {
-		return &train.Feature768Provider{}
+	config, err := parseTrainConfig(args)
+	if err != nil {
+		return err
+	}
+	if config.FitScale {
+		config.SigmoidScale, err = fitSigmoidScale(config.FitEval, qualityDatasetPath)
+		if err != nil {
+			return err
+		}
+	}
+	var filter = dataset.NewFilter(config.Filter)
+
+	layerSizes, activations, err := parseLayers(config.Hidden, config.Dense, config.OutputBuckets, config.Activation)
+	if err != nil {
+		return err
+	}
+	buildFeatureService, buildModel, err := trainArchitecture(config.Arch, layerSizes, activations, 1/config.SigmoidScale)
+	if err != nil {
+		return err
+	}
+	cost, err := ml.NewCost(config.Cost)
+	if err != nil {
+		return err
+	}
+	lambdaSchedule, err := train.ParseLambdaSchedule(config.Lambda)
+	if err != nil {
+		return err
+	}
+	evaluate, err := newNetEvaluator(config.Report, config.Concurrency)
+	if err != nil {
+		return err
+	}
+	var trainConfig = train.Config{
+		Epochs:        config.Epochs,
+		Lambda:        lambdaSchedule,
+		Concurrency:   config.Concurrency,
+		NetFolderPath: config.NetFolder,
+		ResumePath:    config.Resume,
+		StartEpoch:    config.StartEpoch,
+		Experiment:    config,
+		Evaluate:      evaluate,
+	}
+	var model = buildModel()
+	model.InitWeights(rand.New(rand.NewSource(0)))
+	if dataset.IsPackedDataset(config.Dataset) {
+		return train.TrainPacked(config.Dataset, buildFeatureService, filter, config.SigmoidScale, config.Mirror,
+			model, cost, trainConfig)
 	}
 	samples, err := train.LoadDataset(buildFeatureService,
-		gamesFolderPath, sigmoidScale, searchRatio, maxDatasetSize, concurrency, mirrorPos)
+		config.Dataset, filter, config.SigmoidScale, config.MaxDatasetSize, config.Concurrency, config.Mirror)
 	if err != nil {
 		return err
 	}
 	log.Println("Loaded dataset",
 		"size", len(samples))
-	var model = train.NewModel(buildFeatureService().FeatureSize(), hiddenSize)
-	model.InitWeights(rand.New(rand.NewSource(0)))
-	cost, err := ml.NewCost(costName)
+	return train.Train(samples, model, cost, trainConfig)
+}
+
+// Trained output is logit of win probability, outputScale converts it to centipawns in network files.
+func trainArchitecture(arch string, layerSizes []int, activations []nnue.Activation, outputScale float64) (func() train.IFeatureProvider, func() train.IModel, error) {
+	netArch, layout, err := parseArchitecture(arch)
 	if err != nil {
-		return err
+		return nil, nil, err
+	}
+	if netArch == nnue.ArchAbsolute {
+		var buildFeatureService = func() train.IFeatureProvider {
+			return &train.Feature768Provider{}
+		}
+		return buildFeatureService, func() train.IModel {
+			var model = train.NewModel(buildFeatureService().FeatureSize(), layerSizes, activations)
+			model.OutputScale = outputScale
+			return model
+		}, nil
+	}
+	var buildFeatureService = func() train.IFeatureProvider {
+		return &train.FeaturePerspectiveProvider{Layout: layout}
+	}
+	return buildFeatureService, func() train.IModel {
+		var model = train.NewPerspectiveModel(layout, layerSizes, activations)
+		model.OutputScale = outputScale
+		return model
+	}, nil
+}
+
+// parseArchitecture accepts absolute, perspective or kingbuckets.
+func parseArchitecture(arch string) (nnue.Architecture, nnue.PerspectiveLayout, error) {
+	switch arch {
+	case "absolute":
+		return nnue.ArchAbsolute, nnue.PerspectiveLayout{}, nil
+	case "perspective":
+		return nnue.ArchPerspective, nnue.NoKingBuckets, nil
+	case "kingbuckets":
+		return nnue.ArchPerspective, nnue.DefaultKingBuckets, nil
+	}
+	return 0, nnue.PerspectiveLayout{}, fmt.Errorf("bad architecture %v", arch)
+}
+
+// parseLayers returns sizes of hidden, dense and output layers and activations of hidden and dense layers.
+// Single activation is used for every layer.
+func parseLayers(hiddenSize int, denseSizes string, outputBuckets int, activation string) ([]int, []nnue.Activation, error) {
+	var layerSizes = []int{hiddenSize}
+	if denseSizes != "" {
+		for _, field := range strings.Split(denseSizes, ",") {
+			var size, err = strconv.Atoi(strings.TrimSpace(field))
+			if err != nil || size <= 0 {
+				return nil, nil, fmt.Errorf("bad dense layer size %v", field)
+			}
+			layerSizes = append(layerSizes, size)
+		}
+	}
+	var names = strings.Split(activation, ",")
+	if len(names) != 1 && len(names) != len(layerSizes) {
+		return nil, nil, fmt.Errorf("%v activations for %v layers", len(names), len(layerSizes))
+	}
+	var activations []nnue.Activation
+	for i := range layerSizes {
+		var name = names[0]
+		if len(names) != 1 {
+			name = names[i]
+		}
+		var a, err = nnue.ParseActivation(strings.TrimSpace(name))
+		if err != nil {
+			return nil, nil, err
+		}
+		activations = append(activations, a)
 	}
-	return train.Train(samples, epochs, model, cost, concurrency, netFolderPath)
+	return append(layerSizes, outputBuckets), activations, nil
 }
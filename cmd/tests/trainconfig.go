@@ -0,0 +1,110 @@
+package main
+
+import (
+	"flag"
+	"runtime"
+
+	"github.com/ChizhovVadim/CounterGo/internal/dataset"
+)
+
+// trainConfig is experiment of train command, it is saved next to every network.
+type trainConfig struct {
+	Dataset        string               `json:"dataset"` // folder of PGN games or packed .bin file
+	NetFolder      string               `json:"netFolder"`
+	Resume         string               `json:"resume,omitempty"` // network file to continue training from
+	StartEpoch     int                  `json:"startEpoch,omitempty"`
+	Epochs         int                  `json:"epochs"`
+	Concurrency    int                  `json:"concurrency"` // 0 means by number of CPUs
+	MaxDatasetSize int                  `json:"maxDatasetSize"`
+	Mirror         bool                 `json:"mirror"`
+	SigmoidScale   float64              `json:"sigmoidScale"`
+	FitScale       bool                 `json:"fitScale,omitempty"`
+	FitEval        string               `json:"fitEval,omitempty"`
+	Lambda         string               `json:"lambda"`
+	Cost           string               `json:"cost"`
+	Arch           string               `json:"arch"`
+	Hidden         int                  `json:"hidden"`
+	Dense          string               `json:"dense,omitempty"`
+	Activation     string               `json:"activation"`
+	OutputBuckets  int                  `json:"outputBuckets"`
+	Filter         dataset.FilterConfig `json:"filter"`
+	Report         netReportConfig      `json:"report"`
+}
+
+func defaultTrainConfig() trainConfig {
+	return trainConfig{
+		Dataset:        "~/chess/Dataset2023",
+		NetFolder:      "~/chess/net",
+		Epochs:         15,
+		MaxDatasetSize: 50_000_000,
+		Mirror:         true,
+		SigmoidScale:   0.011,
+		Lambda:         "1.0",
+		Cost:           "mse",
+		Arch:           "absolute",
+		Hidden:         512,
+		Activation:     "relu",
+		OutputBuckets:  1,
+		Filter:         dataset.DefaultFilterConfig(),
+		Report: netReportConfig{
+			Quality:       qualityDatasetPath,
+			Tactic:        tacticDatasetPath,
+			TacticNodes:   100_000,
+			MatchOpenings: 16,
+		},
+	}
+}
+
+// parseTrainConfig reads the optional -config JSON file first, command line flags override it.
+func parseTrainConfig(args []string) (trainConfig, error) {
+	var config = defaultTrainConfig()
+	var configPath string
+	newTrainFlagSet(&config, &configPath).Parse(args)
+	if configPath != "" {
+		config = defaultTrainConfig()
+		var err = loadJson(mapPath(configPath), &config)
+		if err != nil {
+			return trainConfig{}, err
+		}
+		newTrainFlagSet(&config, &configPath).Parse(args)
+	}
+	if config.Concurrency == 0 {
+		config.Concurrency = runtime.NumCPU()
+	}
+	config.Dataset = mapPath(config.Dataset)
+	config.NetFolder = mapPath(config.NetFolder)
+	config.Resume = mapPath(config.Resume)
+	config.Report.Quality = mapPath(config.Report.Quality)
+	config.Report.Tactic = mapPath(config.Report.Tactic)
+	return config, nil
+}
+
+func newTrainFlagSet(config *trainConfig, configPath *string) *flag.FlagSet {
+	var flagset = flag.NewFlagSet("", flag.ExitOnError)
+	flagset.StringVar(configPath, "config", *configPath, "path to JSON experiment config, flags override it")
+	flagset.StringVar(&config.Dataset, "dataset", config.Dataset, "folder of PGN games or packed .bin dataset")
+	flagset.StringVar(&config.NetFolder, "netfolder", config.NetFolder, "folder of trained networks, experiment is saved next to every network")
+	flagset.StringVar(&config.Resume, "resume", config.Resume, "path to network file to continue training from")
+	flagset.IntVar(&config.StartEpoch, "startepoch", config.StartEpoch, "epochs done before resume")
+	flagset.IntVar(&config.Epochs, "epochs", config.Epochs, "number of epochs")
+	flagset.IntVar(&config.Concurrency, "concurrency", config.Concurrency, "number of threads, 0 by number of CPUs")
+	flagset.IntVar(&config.MaxDatasetSize, "maxsize", config.MaxDatasetSize, "maximum number of positions loaded from PGN games")
+	flagset.BoolVar(&config.Mirror, "mirror", config.Mirror, "add mirrored positions")
+	flagset.Float64Var(&config.SigmoidScale, "sigmoidscale", config.SigmoidScale, "scale of search score in sigmoid of target")
+	flagset.BoolVar(&config.FitScale, "fitscale", config.FitScale, "fit sigmoid scale on quality dataset instead of -sigmoidscale")
+	flagset.StringVar(&config.FitEval, "fiteval", config.FitEval, "evaluation that produced dataset scores, used by -fitscale")
+	flagset.StringVar(&config.Lambda, "lambda", config.Lambda, "weight of search score in target, the rest is game result; phases lambda:epochs, e.g. 1.0:5,0.7")
+	flagset.StringVar(&config.Cost, "cost", config.Cost, "cost function: mse, abs or ce")
+	flagset.IntVar(&config.Hidden, "hidden", config.Hidden, "hidden layer size")
+	flagset.IntVar(&config.OutputBuckets, "outputbuckets", config.OutputBuckets, "number of outputs selected by piece count")
+	flagset.StringVar(&config.Dense, "dense", config.Dense, "sizes of dense layers after hidden layer, e.g. 32,32")
+	flagset.StringVar(&config.Activation, "activation", config.Activation, "activation of hidden and dense layers: relu, crelu or screlu, comma separated for every layer")
+	flagset.StringVar(&config.Arch, "arch", config.Arch, "network architecture: absolute, perspective or kingbuckets")
+	filterFlags(flagset, &config.Filter)
+	flagset.StringVar(&config.Report.Quality, "quality", config.Report.Quality, "labeled positions for mse of every network, skipped if missing, empty disables")
+	flagset.StringVar(&config.Report.Tactic, "tactic", config.Report.Tactic, "EPD suite solved by every network, skipped if missing, empty disables")
+	flagset.IntVar(&config.Report.TacticNodes, "tacticnodes", config.Report.TacticNodes, "nodes per tactic test")
+	flagset.IntVar(&config.Report.MatchNodes, "matchnodes", config.Report.MatchNodes, "nodes per move of match against the previous best network, 0 disables")
+	flagset.IntVar(&config.Report.MatchOpenings, "matchopenings", config.Report.MatchOpenings, "number of built-in openings of match, each is played with both colours")
+	return flagset
+}
@@ -1,9 +1,14 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"log"
 	"runtime"
+	"strconv"
+	"strings"
 
+	"github.com/ChizhovVadim/CounterGo/internal/dataset"
 	"github.com/ChizhovVadim/CounterGo/internal/evalbuilder"
 	"github.com/ChizhovVadim/CounterGo/internal/tuner"
 )
@@ -11,22 +16,93 @@ import (
 func tunerHandler(args []string) error {
 	var (
 		evalName        = "counter"
-		gamesFolderPath = mapPath("~/chess/Dataset2023")
+		gamesFolderPath = "~/chess/Dataset2023"
 		sigmoidScale    = 0.011
+		fitScale        = false
+		fitEvalName     = ""
 		searchRatio     = 1.0
 		maxDatasetSize  = 6_000_000
-		epochs          = 15
-		concurrency     = runtime.NumCPU()
+		filterConfig    = dataset.DefaultFilterConfig()
+		config          = tuner.DefaultConfig()
 	)
+	config.Concurrency = runtime.NumCPU()
+	config.WeightsPath = "~/chess/tuner/counter-weights.json"
+
+	var flagset = flag.NewFlagSet("", flag.ExitOnError)
+	flagset.StringVar(&evalName, "eval", evalName, "evaluation with tunable features")
+	flagset.StringVar(&gamesFolderPath, "dataset", gamesFolderPath, "folder of PGN games or packed .bin dataset")
+	flagset.IntVar(&maxDatasetSize, "maxsize", maxDatasetSize, "maximum number of positions loaded from PGN games")
+	flagset.Float64Var(&searchRatio, "searchratio", searchRatio, "weight of search score in target, the rest is game result")
+	flagset.IntVar(&config.Epochs, "epochs", config.Epochs, "number of epochs")
+	flagset.IntVar(&config.Concurrency, "concurrency", config.Concurrency, "number of threads")
+	flagset.StringVar(&config.Optimizer, "optimizer", config.Optimizer, "adam, adagrad or sgd")
+	flagset.Float64Var(&config.LearningRate, "lr", config.LearningRate, "learning rate")
+	flagset.Float64Var(&config.Momentum, "momentum", config.Momentum, "momentum of sgd optimizer")
+	flagset.StringVar(&config.Schedule, "schedule", config.Schedule, "learning rate schedule: constant, step, exp or cosine")
+	flagset.Float64Var(&config.DecayRate, "decay", config.DecayRate, "learning rate multiplier of step and exp schedules")
+	flagset.IntVar(&config.DecayEpochs, "decayepochs", config.DecayEpochs, "epochs between learning rate steps")
+	flagset.Float64Var(&config.ValidationRatio, "validation", config.ValidationRatio, "part of dataset held out for validation")
+	flagset.IntVar(&config.Patience, "patience", config.Patience, "stop after epochs without validation improvement, 0 disables")
+	flagset.Var((*groupRatesFlag)(&config.GroupRates), "grouplr", "learning rate scale of feature group pattern=scale, e.g. *PST=2, may be repeated")
+	flagset.StringVar(&config.WeightsPath, "weights", config.WeightsPath, "path to output weights file, use it as -eval of other commands")
+	flagset.StringVar(&config.ResumePath, "resume", config.ResumePath, "path to weights file to continue tuning from")
+	flagset.IntVar(&config.StartEpoch, "startepoch", config.StartEpoch, "epochs done before resume")
+	flagset.Float64Var(&sigmoidScale, "sigmoidscale", sigmoidScale, "scale of search score in sigmoid of target")
+	flagset.BoolVar(&fitScale, "fitscale", fitScale, "fit sigmoid scale on quality dataset instead of -sigmoidscale")
+	flagset.StringVar(&fitEvalName, "fiteval", fitEvalName, "evaluation that produced dataset scores, used by -fitscale")
+	filterFlags(flagset, &filterConfig)
+	flagset.Parse(args)
+	if fitScale {
+		var err error
+		sigmoidScale, err = fitSigmoidScale(fitEvalName, qualityDatasetPath)
+		if err != nil {
+			return err
+		}
+	}
+	gamesFolderPath = mapPath(gamesFolderPath)
+	config.WeightsPath = mapPath(config.WeightsPath)
+	config.ResumePath = mapPath(config.ResumePath)
+	var filter = dataset.NewFilter(filterConfig)
+
 	var buildEvalService = func() tuner.IFeatureProvider {
 		return evalbuilder.Get(evalName)().(tuner.IFeatureProvider)
 	}
+	if dataset.IsPackedDataset(gamesFolderPath) {
+		return tuner.RunTunerPacked(gamesFolderPath, buildEvalService, filter, sigmoidScale, searchRatio, config)
+	}
 	samples, err := tuner.LoadDataset(buildEvalService,
-		gamesFolderPath, sigmoidScale, searchRatio, maxDatasetSize, concurrency)
+		gamesFolderPath, filter, sigmoidScale, searchRatio, maxDatasetSize, config.Concurrency)
 	if err != nil {
 		return err
 	}
 	log.Println("Loaded dataset",
 		"size", len(samples))
-	return tuner.RunTuner(buildEvalService().FeatureSize(), samples, epochs, concurrency)
+	return tuner.RunTuner(buildEvalService(), samples, config)
+}
+
+// groupRatesFlag collects repeated pattern=scale flags.
+type groupRatesFlag []tuner.GroupRate
+
+func (f *groupRatesFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	var items []string
+	for _, item := range *f {
+		items = append(items, fmt.Sprintf("%v=%v", item.Pattern, item.Scale))
+	}
+	return strings.Join(items, ",")
+}
+
+func (f *groupRatesFlag) Set(s string) error {
+	var index = strings.Index(s, "=")
+	if index <= 0 {
+		return fmt.Errorf("group learning rate %q is not pattern=scale", s)
+	}
+	var scale, err = strconv.ParseFloat(s[index+1:], 64)
+	if err != nil {
+		return err
+	}
+	*f = append(*f, tuner.GroupRate{Pattern: s[:index], Scale: scale})
+	return nil
 }
@@ -0,0 +1,109 @@
+package arena
+
+import (
+	"github.com/ChizhovVadim/CounterGo/pkg/common"
+)
+
+const terminationAdjudication = "adjudication"
+
+// Adjudication rules. Zero values disable the corresponding rule.
+type Adjudication struct {
+	ResignScore    int // both engines agree the score is beyond ResignScore centipawns
+	ResignMoves    int // for ResignMoves consecutive moves each
+	DrawScore      int // both engines report scores within ±DrawScore centipawns
+	DrawMoveNumber int // after move DrawMoveNumber
+	DrawMoves      int // for DrawMoves consecutive moves each
+	MaxMoves       int // game is drawn after MaxMoves moves
+
+	TablebasePieces int        // positions with at most TablebasePieces pieces are probed
+	Tablebase       ITablebase `json:"-"`
+}
+
+type ITablebase interface {
+	// ProbeWDL returns 1 if the side to move wins, 0 for a draw and -1 for a loss.
+	ProbeWDL(p *common.Position) (wdl int, ok bool)
+}
+
+type adjudicator struct {
+	config    Adjudication
+	winCount  [common.COLOUR_NB]int
+	lossCount [common.COLOUR_NB]int
+	drawCount int
+	plies     int
+}
+
+func newAdjudicator(config Adjudication) *adjudicator {
+	return &adjudicator{config: config}
+}
+
+// onMove registers the score reported by the side that made the move (side to move perspective).
+func (a *adjudicator) onMove(p *common.Position, si common.SearchInfo) {
+	a.plies++
+	var side = sideIndex(p.WhiteMove)
+	var score = scoreCentipawns(si.Score)
+
+	if a.config.ResignScore != 0 {
+		a.winCount[side] = incOrReset(a.winCount[side], score >= a.config.ResignScore)
+		a.lossCount[side] = incOrReset(a.lossCount[side], score <= -a.config.ResignScore)
+	}
+
+	if a.config.DrawMoves != 0 {
+		var moveNumber = (a.plies + 1) / 2
+		a.drawCount = incOrReset(a.drawCount, moveNumber >= a.config.DrawMoveNumber &&
+			-a.config.DrawScore <= score && score <= a.config.DrawScore)
+	}
+}
+
+// adjudicate is called before the side to move searches.
+func (a *adjudicator) adjudicate(p *common.Position) (result int, comment string, ok bool) {
+	if a.config.Tablebase != nil && a.config.TablebasePieces != 0 &&
+		common.PopCount(p.White|p.Black) <= a.config.TablebasePieces {
+		if wdl, ok := a.config.Tablebase.ProbeWDL(p); ok {
+			if wdl == 0 {
+				return gameResultDraw, "draw by tablebase adjudication", true
+			}
+			if (wdl > 0) == p.WhiteMove {
+				return gameResultWhiteWins, "white wins by tablebase adjudication", true
+			}
+			return gameResultBlackWins, "black wins by tablebase adjudication", true
+		}
+	}
+
+	if a.config.ResignScore != 0 && a.config.ResignMoves != 0 {
+		var n = a.config.ResignMoves
+		if a.winCount[common.SideWhite] >= n && a.lossCount[common.SideBlack] >= n {
+			return gameResultWhiteWins, "black resigns by adjudication", true
+		}
+		if a.winCount[common.SideBlack] >= n && a.lossCount[common.SideWhite] >= n {
+			return gameResultBlackWins, "white resigns by adjudication", true
+		}
+	}
+
+	if a.config.DrawMoves != 0 && a.drawCount >= 2*a.config.DrawMoves {
+		return gameResultDraw, "draw by adjudication", true
+	}
+
+	if a.config.MaxMoves != 0 && a.plies >= 2*a.config.MaxMoves {
+		return gameResultDraw, "draw by maximum game length", true
+	}
+
+	return 0, "", false
+}
+
+func scoreCentipawns(score common.UciScore) int {
+	const mateScore = 30_000
+	if score.Mate > 0 {
+		return mateScore
+	}
+	if score.Mate < 0 {
+		return -mateScore
+	}
+	return score.Centipawns
+}
+
+func incOrReset(count int, condition bool) int {
+	if condition {
+		return count + 1
+	}
+	return 0
+}
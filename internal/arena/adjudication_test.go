@@ -0,0 +1,72 @@
+package arena
+
+import (
+	"testing"
+
+	"github.com/ChizhovVadim/CounterGo/pkg/common"
+)
+
+type fakeTablebase struct {
+	wdl int
+	ok  bool
+}
+
+func (tb *fakeTablebase) ProbeWDL(p *common.Position) (int, bool) {
+	return tb.wdl, tb.ok
+}
+
+func TestAdjudicate(t *testing.T) {
+	const kqk = "8/8/8/4k3/8/8/8/KQ6 w - - 0 1"
+	var resign = Adjudication{ResignScore: 500, ResignMoves: 2}
+	var draw = Adjudication{DrawScore: 10, DrawMoveNumber: 1, DrawMoves: 2}
+	var tests = []struct {
+		name   string
+		config Adjudication
+		scores []int // by side to move of each ply, white moves first
+		fen    string
+		result int
+		ok     bool
+	}{
+		{"white wins", resign, []int{600, -600, 600, -600}, "", gameResultWhiteWins, true},
+		{"black wins", resign, []int{-600, 600, -600, 600}, "", gameResultBlackWins, true},
+		{"resign moves", resign, []int{600, -600, 600}, "", 0, false},
+		{"resign reset", resign, []int{600, -600, 100, -600, 600, -600}, "", 0, false},
+		{"resign disabled", Adjudication{ResignMoves: 2}, []int{600, -600, 600, -600}, "", 0, false},
+		{"draw", draw, []int{0, 5, -10, 0}, "", gameResultDraw, true},
+		{"draw score", draw, []int{0, 5, 50, 0}, "", 0, false},
+		{"draw move number", Adjudication{DrawScore: 10, DrawMoveNumber: 3, DrawMoves: 2}, []int{0, 0, 0, 0}, "", 0, false},
+		{"max moves", Adjudication{MaxMoves: 2}, []int{900, -900, 900, -900}, "", gameResultDraw, true},
+		{"max moves not reached", Adjudication{MaxMoves: 2}, []int{900, -900, 900}, "", 0, false},
+		{"tablebase white wins", Adjudication{TablebasePieces: 3, Tablebase: &fakeTablebase{1, true}}, nil, kqk, gameResultWhiteWins, true},
+		{"tablebase black wins", Adjudication{TablebasePieces: 3, Tablebase: &fakeTablebase{-1, true}}, nil, kqk, gameResultBlackWins, true},
+		{"tablebase side to move", Adjudication{TablebasePieces: 3, Tablebase: &fakeTablebase{1, true}}, nil, "8/8/8/4k3/8/8/8/KQ6 b - - 0 1", gameResultBlackWins, true},
+		{"tablebase draw", Adjudication{TablebasePieces: 3, Tablebase: &fakeTablebase{0, true}}, nil, kqk, gameResultDraw, true},
+		{"tablebase miss", Adjudication{TablebasePieces: 3, Tablebase: &fakeTablebase{1, false}}, nil, kqk, 0, false},
+		{"tablebase pieces", Adjudication{TablebasePieces: 2, Tablebase: &fakeTablebase{1, true}}, nil, kqk, 0, false},
+		{"tablebase disabled", Adjudication{Tablebase: &fakeTablebase{1, true}}, nil, kqk, 0, false},
+	}
+	for _, test := range tests {
+		var white, _ = common.NewPositionFromFEN(common.InitialPositionFen)
+		var black, _ = common.NewPositionFromFEN("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1")
+		var adjudicator = newAdjudicator(test.config)
+		for i, score := range test.scores {
+			var p = &white
+			if i%2 == 1 {
+				p = &black
+			}
+			adjudicator.onMove(p, common.SearchInfo{Score: common.UciScore{Centipawns: score}})
+		}
+		var p = white
+		if test.fen != "" {
+			var err error
+			p, err = common.NewPositionFromFEN(test.fen)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		result, comment, ok := adjudicator.adjudicate(&p)
+		if ok != test.ok || ok && result != test.result {
+			t.Errorf("%v: result %v %q ok %v, expected %v ok %v", test.name, result, comment, ok, test.result, test.ok)
+		}
+	}
+}
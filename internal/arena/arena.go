@@ -2,6 +2,9 @@ package arena
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"runtime"
 	"sync"
@@ -11,40 +14,117 @@ import (
 
 func Run(
 	ctx context.Context,
-	gameConcurrency int,
-	tc TimeControl,
-	engineBuilder func(experiment bool) IEngine,
+	config Config,
+	engineBuilder func(experiment bool) (IEngine, error),
 ) error {
+	var _, err = RunMatch(ctx, config, engineBuilder)
+	return err
+}
+
+// MatchResult is score of experiment engine.
+type MatchResult struct {
+	Wins   int
+	Losses int
+	Draws  int
+}
+
+// Score is fraction of points of experiment engine.
+func (r MatchResult) Score() float64 {
+	var games = r.Wins + r.Losses + r.Draws
+	if games == 0 {
+		return 0.5
+	}
+	return (float64(r.Wins) + 0.5*float64(r.Draws)) / float64(games)
+}
+
+// RunMatch is Run that returns score of experiment engine in all finished games.
+func RunMatch(
+	ctx context.Context,
+	config Config,
+	engineBuilder func(experiment bool) (IEngine, error),
+) (MatchResult, error) {
 	log.Println("arena started")
 	defer log.Println("arena finished")
 
 	log.Println("NumCPU", runtime.NumCPU(),
 		"GOMAXPROCS", runtime.GOMAXPROCS(0),
-		"gameConcurrency", gameConcurrency)
+		"gameConcurrency", config.GameConcurrency)
 
-	log.Printf("%+v\n", tc)
+	log.Printf("%+v\n", config.TimeControl)
+
+	var result MatchResult
+	openings, err := loadOpeningFens(config.Openings)
+	if err != nil {
+		return result, err
+	}
+	log.Println("openings", len(openings))
+
+	var resumed []gameResult
+	var finished = make(map[arenaJournalKey]struct{})
+	var journal *journal
+	if config.StatePath != "" {
+		var entries []journalEntry
+		journal, entries, err = openJournal(config.StatePath)
+		if err != nil {
+			return result, err
+		}
+		defer journal.Close()
+		resumed, err = resumeGames(entries, openings)
+		if err != nil {
+			return result, err
+		}
+		for _, res := range resumed {
+			finished[arenaJournalKey{opening: pairIndex(res.gameInfo.gameNumber), engineAIsWhite: res.gameInfo.engineAIsWhite}] = struct{}{}
+		}
+		log.Println("resumed games", len(resumed))
+	}
 
 	g, ctx := errgroup.WithContext(ctx)
 
 	var gameInfos = make(chan gameInfo)
 	var gameResults = make(chan gameResult)
 
+	var pgnWriter *pgnWriter
+	if config.PgnPath != "" {
+		pgnWriter, err = newPgnWriter(config.PgnPath, config.TimeControl)
+		if err != nil {
+			return result, err
+		}
+		defer pgnWriter.Close()
+	}
+
 	g.Go(func() error {
 		defer close(gameInfos)
-		return loadOpenings(ctx, gameInfos)
+		return loadOpenings(ctx, openings, finished, gameInfos)
 	})
 
+	var sprt *sprt
+	if config.Sprt != nil {
+		sprt = newSprt(*config.Sprt)
+	}
+
 	g.Go(func() error {
-		return showResults(ctx, gameResults)
+		return showResults(ctx, gameResults, resumed, pgnWriter, journal, sprt, &result)
 	})
 
 	var wg = &sync.WaitGroup{}
 
-	for i := 0; i < gameConcurrency; i++ {
+	for i := 0; i < config.GameConcurrency; i++ {
 		wg.Add(1)
 		g.Go(func() error {
 			defer wg.Done()
-			return playGames(ctx, tc, gameInfos, gameResults, engineBuilder(false), engineBuilder(true))
+			engineA, err := engineBuilder(false)
+			if err != nil {
+				return err
+			}
+			defer closeEngine(engineA)
+			engineB, err := engineBuilder(true)
+			if err != nil {
+				return err
+			}
+			defer closeEngine(engineB)
+			return playGames(ctx, config.TimeControl, config.Adjudication, gameInfos, gameResults,
+				engineA, engineB, engineName(engineA, "Base"), engineName(engineB, "Experiment"))
 		})
 	}
 
@@ -54,21 +134,32 @@ func Run(
 		return nil
 	})
 
-	return g.Wait()
+	err = g.Wait()
+	if errors.Is(err, errSprtFinished) {
+		return result, nil
+	}
+	return result, err
 }
 
 func playGames(
 	ctx context.Context,
 	tc TimeControl,
+	adjudication Adjudication,
 	gameInfos <-chan gameInfo,
 	gameResults chan<- gameResult,
 	engineA, engineB IEngine,
+	engineAName, engineBName string,
 ) error {
 	for gameInfo := range gameInfos {
-		var res, err = playGame(ctx, engineA, engineB, tc, gameInfo)
+		var res, err = playGame(ctx, engineA, engineB, tc, adjudication, gameInfo)
 		if err != nil {
 			return err
 		}
+		if gameInfo.engineAIsWhite {
+			res.whiteName, res.blackName = engineAName, engineBName
+		} else {
+			res.whiteName, res.blackName = engineBName, engineAName
+		}
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
@@ -77,3 +168,54 @@ func playGames(
 	}
 	return nil
 }
+
+// closeEngine stops engines that own resources, e.g. external UCI processes.
+func closeEngine(eng IEngine) {
+	if closer, ok := eng.(io.Closer); ok {
+		closer.Close()
+	}
+}
+
+func engineName(eng IEngine, defaultName string) string {
+	if named, ok := eng.(interface{ Name() string }); ok && named.Name() != "" {
+		return named.Name()
+	}
+	return defaultName
+}
+
+// resumeGames restores finished games from the journal. The journal must be written with the same openings.
+func resumeGames(entries []journalEntry, openings []string) ([]gameResult, error) {
+	var result []gameResult
+	var seen = make(map[arenaJournalKey]struct{})
+	for _, entry := range entries {
+		var res, ok = parseGameResult(entry.Result)
+		if !ok || entry.BaseColour == "" {
+			continue
+		}
+		if entry.Opening < 0 || entry.Opening >= len(openings) || entry.Fen != openings[entry.Opening] {
+			return nil, fmt.Errorf("state file does not match openings: round %v", entry.Round)
+		}
+		var key = entry.arenaKey()
+		if _, found := seen[key]; found {
+			continue
+		}
+		seen[key] = struct{}{}
+		result = append(result, gameResult{
+			gameInfo: gameInfo{
+				opening:        entry.Fen,
+				engineAIsWhite: key.engineAIsWhite,
+				gameNumber:     entry.Round,
+			},
+			whiteName: entry.White,
+			blackName: entry.Black,
+			comment:   entry.Comment,
+			result:    res,
+		})
+	}
+	return result, nil
+}
+
+// pairIndex is the index of the opening a game is played from.
+func pairIndex(gameNumber int) int {
+	return (gameNumber - 1) / 2
+}
@@ -0,0 +1,165 @@
+package arena
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+)
+
+type crosstable struct {
+	names  []string
+	games  [][]int
+	points [][]float64 // points[i][j] scored by player i against player j
+}
+
+func newCrosstable(players []Player) *crosstable {
+	var n = len(players)
+	var t = &crosstable{
+		names:  make([]string, n),
+		games:  make([][]int, n),
+		points: make([][]float64, n),
+	}
+	for i, player := range players {
+		t.names[i] = player.Name
+		t.games[i] = make([]int, n)
+		t.points[i] = make([]float64, n)
+	}
+	return t
+}
+
+func (t *crosstable) addGame(white, black int, result int) {
+	var whitePoints float64
+	switch result {
+	case gameResultWhiteWins:
+		whitePoints = 1
+	case gameResultDraw:
+		whitePoints = 0.5
+	}
+	t.games[white][black]++
+	t.games[black][white]++
+	t.points[white][black] += whitePoints
+	t.points[black][white] += 1 - whitePoints
+}
+
+func (t *crosstable) totalGames() int {
+	var result int
+	for i := range t.games {
+		for j := i + 1; j < len(t.games); j++ {
+			result += t.games[i][j]
+		}
+	}
+	return result
+}
+
+type rating struct {
+	elo   float64
+	error float64 // 95% confidence
+}
+
+// computeRatings finds maximum likelihood logistic ratings (as Ordo and BayesElo do),
+// counting a draw as half a win and half a loss. Every played pairing gets one
+// virtual draw as a prior, so perfect scores stay finite.
+// Error bars come from the diagonal of the Fisher information.
+func (t *crosstable) computeRatings() []rating {
+	const (
+		priorDraws = 1
+		iterations = 1000
+		eloScale   = 400 / math.Ln10
+	)
+	var n = len(t.names)
+	var r = make([]float64, n) // in natural logistic units
+	var information = make([]float64, n)
+	for iter := 0; iter < iterations; iter++ {
+		var maxStep float64
+		for i := 0; i < n; i++ {
+			var gradient, hessian float64
+			for j := 0; j < n; j++ {
+				if i == j || t.games[i][j] == 0 {
+					continue
+				}
+				var games = float64(t.games[i][j] + priorDraws)
+				var points = t.points[i][j] + 0.5*priorDraws
+				var p = 1 / (1 + math.Exp(r[j]-r[i]))
+				gradient += points - games*p
+				hessian += games * p * (1 - p)
+			}
+			information[i] = hessian
+			if hessian > 0 {
+				var step = gradient / hessian
+				r[i] += step
+				maxStep = math.Max(maxStep, math.Abs(step))
+			}
+		}
+		// players without games stay at zero
+		var mean float64
+		var rated int
+		for i := range r {
+			if information[i] > 0 {
+				mean += r[i]
+				rated++
+			}
+		}
+		if rated != 0 {
+			mean /= float64(rated)
+		}
+		for i := range r {
+			if information[i] > 0 {
+				r[i] -= mean
+			}
+		}
+		if maxStep < 1e-9 {
+			break
+		}
+	}
+	var result = make([]rating, n)
+	for i := range result {
+		result[i].elo = r[i] * eloScale
+		if information[i] > 0 {
+			result[i].error = 1.96 * eloScale / math.Sqrt(information[i])
+		}
+	}
+	return result
+}
+
+func (t *crosstable) print() {
+	var ratings = t.computeRatings()
+	var order = make([]int, len(t.names))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return ratings[order[i]].elo > ratings[order[j]].elo
+	})
+
+	var sb = &strings.Builder{}
+	fmt.Fprintf(sb, "%4v %-20v %7v %6v %6v %6v", "Rank", "Name", "Elo", "+/-", "Games", "Score")
+	for rank := range order {
+		fmt.Fprintf(sb, " %6v", rank+1)
+	}
+	sb.WriteString("\n")
+	for rank, i := range order {
+		var games int
+		var points float64
+		for j := range t.names {
+			games += t.games[i][j]
+			points += t.points[i][j]
+		}
+		var score float64
+		if games != 0 {
+			score = 100 * points / float64(games)
+		}
+		fmt.Fprintf(sb, "%4v %-20v %7.1f %6.1f %6v %5.1f%%",
+			rank+1, t.names[i], ratings[i].elo, ratings[i].error, games, score)
+		for _, j := range order {
+			if i == j {
+				fmt.Fprintf(sb, " %6v", "-")
+			} else {
+				fmt.Fprintf(sb, " %6v", fmt.Sprintf("%v/%v", t.points[i][j], t.games[i][j]))
+			}
+		}
+		sb.WriteString("\n")
+	}
+	log.Printf("Standings after %v games\n%v", t.totalGames(), sb.String())
+}
@@ -0,0 +1,66 @@
+package arena
+
+import (
+	"math"
+	"testing"
+)
+
+func TestComputeRatings(t *testing.T) {
+	type pairing struct {
+		player, opponent int
+		games            int
+		points           float64 // scored by player
+	}
+	// elo of logistic win odds
+	var elo = func(odds float64) float64 { return 400 * math.Log10(odds) }
+	var tests = []struct {
+		name     string
+		players  int
+		pairings []pairing
+		elos     []float64
+	}{
+		{"equal", 2, []pairing{{0, 1, 100, 50}}, []float64{0, 0}},
+		// with the prior draw the score is 75%
+		{"odds", 2, []pairing{{0, 1, 99, 74.5}}, []float64{elo(3) / 2, -elo(3) / 2}},
+		{"perfect score", 2, []pairing{{0, 1, 10, 10}}, []float64{elo(21) / 2, -elo(21) / 2}},
+		{"transitive", 3, []pairing{{0, 1, 99, 74.5}, {1, 2, 99, 74.5}, {0, 2, 99, 89.5}},
+			[]float64{elo(3), 0, -elo(3)}},
+		{"not played", 3, []pairing{{0, 1, 99, 74.5}}, []float64{elo(3) / 2, -elo(3) / 2, 0}},
+	}
+	for _, test := range tests {
+		var table = newCrosstable(make([]Player, test.players))
+		for _, p := range test.pairings {
+			table.games[p.player][p.opponent] += p.games
+			table.games[p.opponent][p.player] += p.games
+			table.points[p.player][p.opponent] += p.points
+			table.points[p.opponent][p.player] += float64(p.games) - p.points
+		}
+		var ratings = table.computeRatings()
+		for i, rating := range ratings {
+			if math.Abs(rating.elo-test.elos[i]) > 1e-6 {
+				t.Errorf("%v: player %v elo %v, expected %v", test.name, i, rating.elo, test.elos[i])
+			}
+		}
+	}
+}
+
+func TestComputeRatingsError(t *testing.T) {
+	var table = newCrosstable(make([]Player, 3))
+	table.addGame(0, 1, gameResultWhiteWins)
+	table.addGame(1, 0, gameResultDraw)
+	var ratings = table.computeRatings()
+	// 3 games with the prior draw, both players score near 50%, information is games*p*(1-p)
+	var p = 1 / (1 + math.Exp((ratings[1].elo-ratings[0].elo)*math.Ln10/400))
+	var expected = 1.96 * 400 / math.Ln10 / math.Sqrt(3*p*(1-p))
+	for i := 0; i < 2; i++ {
+		if math.Abs(ratings[i].error-expected) > 1e-6 {
+			t.Errorf("player %v error %v, expected %v", i, ratings[i].error, expected)
+		}
+	}
+	if ratings[2].elo != 0 || ratings[2].error != 0 {
+		t.Errorf("player without games %+v", ratings[2])
+	}
+	if table.totalGames() != 2 {
+		t.Errorf("total games %v", table.totalGames())
+	}
+}
@@ -18,9 +18,30 @@ type IEngine interface {
 	Search(ctx context.Context, searchParams common.SearchParams) common.SearchInfo
 }
 
+// TimeControl is either FixedNodes, FixedTime or a clock with Base time,
+// Increment per move and optionally Moves per time session.
 type TimeControl struct {
 	FixedNodes int
 	FixedTime  time.Duration
+	Base       time.Duration
+	Increment  time.Duration
+	Moves      int
+	Margin     time.Duration // overrun tolerated before a time loss
+}
+
+const (
+	terminationNormal      = "normal"
+	terminationTimeForfeit = "time forfeit"
+)
+
+type Config struct {
+	GameConcurrency int
+	TimeControl     TimeControl
+	Adjudication    Adjudication
+	Openings        Openings
+	PgnPath         string      // optional, finished games are appended to this file
+	Sprt            *SprtConfig // optional, stop when the test is decided
+	StatePath       string      // optional, journal of finished games to resume from
 }
 
 type gameInfo struct {
@@ -30,8 +51,12 @@ type gameInfo struct {
 }
 
 type gameResult struct {
-	gameInfo  gameInfo
-	positions []common.Position
-	comment   string
-	result    int
+	gameInfo    gameInfo
+	positions   []common.Position
+	searchInfos []common.SearchInfo
+	whiteName   string
+	blackName   string
+	comment     string
+	termination string
+	result      int
 }
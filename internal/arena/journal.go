@@ -0,0 +1,124 @@
+package arena
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// journalEntry is a finished game. Tournament entries are identified by opening index and players,
+// arena entries by opening index and colour of the base engine.
+type journalEntry struct {
+	Round      int    `json:"round"`
+	Opening    int    `json:"opening"`
+	Fen        string `json:"fen,omitempty"`
+	White      string `json:"white"`
+	Black      string `json:"black"`
+	BaseColour string `json:"baseColour,omitempty"`
+	Result     string `json:"result"`
+	Comment    string `json:"comment,omitempty"`
+}
+
+type journalKey struct {
+	opening      int
+	white, black string
+}
+
+func (e *journalEntry) key() journalKey {
+	return journalKey{opening: e.Opening, white: e.White, black: e.Black}
+}
+
+type arenaJournalKey struct {
+	opening        int
+	engineAIsWhite bool
+}
+
+func (e *journalEntry) arenaKey() arenaJournalKey {
+	return arenaJournalKey{opening: e.Opening, engineAIsWhite: e.BaseColour == colourWhite}
+}
+
+const (
+	colourWhite = "white"
+	colourBlack = "black"
+)
+
+// journal is an append-only file with one JSON entry per line.
+// A line is written with a single append and synced, so a crash can leave at most
+// one incomplete last line, which is dropped on the next open. Bad lines before it are an error.
+type journal struct {
+	file *os.File
+}
+
+func openJournal(path string) (*journal, []journalEntry, error) {
+	var file, err = os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, nil, err
+	}
+	entries, validSize, err := readJournal(file)
+	if err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("journal %v: %w", path, err)
+	}
+	err = file.Truncate(validSize)
+	if err == nil {
+		_, err = file.Seek(validSize, io.SeekStart)
+	}
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	return &journal{file: file}, entries, nil
+}
+
+func readJournal(r io.Reader) ([]journalEntry, int64, error) {
+	var entries []journalEntry
+	var validSize int64
+	var reader = bufio.NewReader(r)
+	for lineNumber := 1; ; lineNumber++ {
+		var line, err = reader.ReadBytes('\n')
+		if err == io.EOF {
+			// incomplete last line
+			return entries, validSize, nil
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		var entry journalEntry
+		if len(bytes.TrimSpace(line)) != 0 {
+			var parseErr = json.Unmarshal(line, &entry)
+			if parseErr != nil {
+				var rest, err = io.ReadAll(reader)
+				if err != nil {
+					return nil, 0, err
+				}
+				if len(bytes.TrimSpace(rest)) != 0 {
+					return nil, 0, fmt.Errorf("line %v: %w", lineNumber, parseErr)
+				}
+				// torn last line
+				return entries, validSize, nil
+			}
+			entries = append(entries, entry)
+		}
+		validSize += int64(len(line))
+	}
+}
+
+func (j *journal) Append(entry journalEntry) error {
+	var data, err = json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	_, err = j.file.Write(data)
+	if err != nil {
+		return err
+	}
+	return j.file.Sync()
+}
+
+func (j *journal) Close() error {
+	return j.file.Close()
+}
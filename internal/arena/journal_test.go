@@ -0,0 +1,112 @@
+package arena
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadJournal(t *testing.T) {
+	const (
+		first  = `{"round":1,"opening":0,"white":"a","black":"b","result":"1-0"}` + "\n"
+		second = `{"round":1,"opening":0,"white":"b","black":"a","result":"1/2-1/2"}` + "\n"
+		torn   = `{"round":2,"opening":1,"wh`
+	)
+	var tests = []struct {
+		name      string
+		data      string
+		entries   int
+		validSize int
+		err       string
+	}{
+		{"empty", "", 0, 0, ""},
+		{"entries", first + second, 2, len(first + second), ""},
+		{"blank lines", first + "\n" + second, 2, len(first + "\n" + second), ""},
+		{"incomplete last line", first + second + torn, 2, len(first + second), ""},
+		{"torn last line", first + second + torn + "\n", 2, len(first + second), ""},
+		{"torn last line with blanks", first + torn + "\n\n", 1, len(first), ""},
+		{"corrupted", first + torn + "\n" + second, 0, 0, "line 2"},
+		{"corrupted first line", "garbage\n" + first, 0, 0, "line 1"},
+	}
+	for _, test := range tests {
+		var entries, validSize, err = readJournal(strings.NewReader(test.data))
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%v: error %v, expected %v", test.name, err, test.err)
+			}
+			continue
+		}
+		if err != nil || len(entries) != test.entries || validSize != int64(test.validSize) {
+			t.Errorf("%v: %v entries, valid size %v, error %v, expected %v entries, valid size %v",
+				test.name, len(entries), validSize, err, test.entries, test.validSize)
+		}
+	}
+}
+
+func TestJournalReplay(t *testing.T) {
+	var path = filepath.Join(t.TempDir(), "state.jsonl")
+	var games = []journalEntry{
+		{Round: 1, Opening: 0, White: "a", Black: "b", BaseColour: colourWhite, Result: "1-0"},
+		{Round: 1, Opening: 0, White: "b", Black: "a", BaseColour: colourBlack, Result: "0-1", Comment: "white resigns by adjudication"},
+	}
+	var journal, entries, err = openJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, game := range games {
+		if err = journal.Append(game); err != nil {
+			t.Fatal(err)
+		}
+	}
+	journal.Close()
+
+	// a crash while writing the third game
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"round":2,"open`)
+	file.Close()
+
+	journal, entries, err = openJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(games) || entries[0] != games[0] || entries[1] != games[1] {
+		t.Fatalf("replayed %+v", entries)
+	}
+	if entries[1].arenaKey() != (arenaJournalKey{opening: 0, engineAIsWhite: false}) {
+		t.Fatalf("arena key %+v", entries[1].arenaKey())
+	}
+	var third = journalEntry{Round: 2, Opening: 1, White: "a", Black: "b", Result: "1/2-1/2"}
+	if err = journal.Append(third); err != nil {
+		t.Fatal(err)
+	}
+	journal.Close()
+
+	journal, entries, err = openJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	journal.Close()
+	if len(entries) != 3 || entries[2] != third {
+		t.Fatalf("replayed after append %+v", entries)
+	}
+
+	// corruption before the last line is not dropped silently
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[1] = '#'
+	if err = os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = openJournal(path); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Fatalf("corrupted journal error %v", err)
+	}
+	if after, _ := os.ReadFile(path); string(after) != string(data) {
+		t.Fatal("corrupted journal is truncated")
+	}
+}
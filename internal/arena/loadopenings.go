@@ -14,29 +14,40 @@ var openingsTxt string
 
 func loadOpenings(
 	ctx context.Context,
+	openings []string,
+	finished map[arenaJournalKey]struct{},
 	gameInfos chan<- gameInfo,
 ) error {
+	for i, fen := range openings {
+		for _, engineAIsWhite := range [2]bool{true, false} {
+			if _, found := finished[arenaJournalKey{opening: i, engineAIsWhite: engineAIsWhite}]; found {
+				continue
+			}
+			var gameNumber = 1 + 2*i
+			if !engineAIsWhite {
+				gameNumber++
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case gameInfos <- gameInfo{opening: fen, engineAIsWhite: engineAIsWhite, gameNumber: gameNumber}:
+			}
+		}
+	}
 
-	var openings = getOpenings()
+	return nil
+}
 
-	for i, opening := range openings {
+func builtinOpenings() ([]string, error) {
+	var result []string
+	for _, opening := range getOpenings() {
 		var fen, err = parseOpening(opening)
 		if err != nil {
-			return err
-		}
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case gameInfos <- gameInfo{opening: fen, engineAIsWhite: true, gameNumber: 1 + 2*i}:
-		}
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case gameInfos <- gameInfo{opening: fen, engineAIsWhite: false, gameNumber: 1 + 2*i + 1}:
+			return nil, err
 		}
+		result = append(result, fen)
 	}
-
-	return nil
+	return result, nil
 }
 
 func parseOpening(opening string) (string, error) {
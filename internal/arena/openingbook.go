@@ -0,0 +1,156 @@
+package arena
+
+import (
+	"bufio"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ChizhovVadim/CounterGo/internal/pgn"
+	"github.com/ChizhovVadim/CounterGo/pkg/common"
+)
+
+// Openings configures where start positions come from and in which order they are played.
+type Openings struct {
+	Path    string // EPD or PGN file, built-in openings if empty
+	Random  bool
+	Seed    int64
+	Repeats int // each opening is played Repeats times with both colours
+	Count   int // only the first Count openings of file are played, all if 0
+}
+
+// loadOpeningFens returns start positions in play order.
+func loadOpeningFens(config Openings) ([]string, error) {
+	var fens []string
+	var err error
+	if config.Path == "" {
+		fens, err = builtinOpenings()
+	} else {
+		fens, err = LoadOpeningFile(config.Path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(fens) == 0 {
+		return nil, fmt.Errorf("no openings")
+	}
+	if config.Count > 0 && config.Count < len(fens) {
+		fens = fens[:config.Count]
+	}
+
+	var repeats = config.Repeats
+	if repeats < 1 {
+		repeats = 1
+	}
+	var r = rand.New(rand.NewSource(config.Seed))
+	var result = make([]string, 0, repeats*len(fens))
+	for i := 0; i < repeats; i++ {
+		var round = append([]string(nil), fens...)
+		if config.Random {
+			r.Shuffle(len(round), func(i, j int) {
+				round[i], round[j] = round[j], round[i]
+			})
+		}
+		result = append(result, round...)
+	}
+	return result, nil
+}
+
+// LoadOpeningFile reads start positions from EPD/FEN lines or from the final positions of PGN games.
+// Polyglot .bin books are rejected rather than misread as EPD.
+func LoadOpeningFile(path string) ([]string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".pgn":
+		return loadPgnOpenings(path)
+	case ".bin":
+		//TODO polyglot reader: Random64 key table, big-endian 16 byte entries, weighted move checked by legal moves
+		return nil, fmt.Errorf("polyglot books are not supported, convert to EPD or PGN: %v", path)
+	default:
+		return loadEpdOpenings(path)
+	}
+}
+
+func loadEpdOpenings(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var result []string
+	var scanner = bufio.NewScanner(file)
+	for scanner.Scan() {
+		var line = strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "//") || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var fen, err = parseEpdPosition(line)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, fen)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// parseEpdPosition accepts both EPD (4 fields and opcodes) and full FEN lines.
+func parseEpdPosition(line string) (string, error) {
+	var fields = strings.Fields(line)
+	if len(fields) < 4 {
+		return "", fmt.Errorf("bad epd %v", line)
+	}
+	var n = 4
+	if len(fields) >= 6 && isNumber(fields[4]) && isNumber(fields[5]) {
+		n = 6
+	}
+	var pos, err = common.NewPositionFromFEN(strings.Join(fields[:n], " "))
+	if err != nil {
+		return "", err
+	}
+	return pos.String(), nil
+}
+
+func isNumber(s string) bool {
+	var _, err = strconv.Atoi(s)
+	return err == nil
+}
+
+func loadPgnOpenings(path string) ([]string, error) {
+	var result []string
+	var err = pgn.WalkPgnFile(path, func(gameRaw pgn.GameRaw) error {
+		if _, ok := pgn.TagValue(gameRaw.Tags, "Result"); !ok {
+			gameRaw.Tags = append(gameRaw.Tags, pgn.Tag{Key: "Result", Value: pgn.GameResultNone})
+		}
+		var game, err = pgn.ParseGame(gameRaw)
+		if err != nil {
+			return err
+		}
+		var startFen = game.Fen
+		if startFen == "" {
+			startFen = common.InitialPositionFen
+		}
+		pos, err := common.NewPositionFromFEN(startFen)
+		if err != nil {
+			return err
+		}
+		for _, item := range game.Items {
+			var child common.Position
+			if !pos.MakeMove(item.Move, &child) {
+				return fmt.Errorf("illegal move %v in opening", item.Move)
+			}
+			pos = child
+		}
+		result = append(result, pos.String())
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
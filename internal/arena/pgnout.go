@@ -0,0 +1,69 @@
+package arena
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/ChizhovVadim/CounterGo/internal/pgn"
+	"github.com/ChizhovVadim/CounterGo/pkg/common"
+)
+
+type pgnWriter struct {
+	file *os.File
+	tc   TimeControl
+	date string
+}
+
+func newPgnWriter(path string, tc TimeControl) (*pgnWriter, error) {
+	var file, err = os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &pgnWriter{
+		file: file,
+		tc:   tc,
+		date: time.Now().Format("2006.01.02"),
+	}, nil
+}
+
+func (w *pgnWriter) Close() error {
+	return w.file.Close()
+}
+
+func (w *pgnWriter) Write(res gameResult) error {
+	var sResult = gameResultString(res.result)
+	var tags = []pgn.Tag{
+		{Key: "Event", Value: "arena"},
+		{Key: "Site", Value: "?"},
+		{Key: "Date", Value: w.date},
+		{Key: "Round", Value: strconv.Itoa(res.gameInfo.gameNumber)},
+		{Key: "White", Value: res.whiteName},
+		{Key: "Black", Value: res.blackName},
+		{Key: "Result", Value: sResult},
+	}
+	if res.gameInfo.opening != common.InitialPositionFen {
+		tags = append(tags,
+			pgn.Tag{Key: "FEN", Value: res.gameInfo.opening},
+			pgn.Tag{Key: "SetUp", Value: "1"})
+	}
+	tags = append(tags,
+		pgn.Tag{Key: "TimeControl", Value: w.tc.String()},
+		pgn.Tag{Key: "Termination", Value: res.termination})
+
+	var game = pgn.Game{
+		Result: sResult,
+		Fen:    res.gameInfo.opening,
+	}
+	for i, si := range res.searchInfos {
+		game.Items = append(game.Items, pgn.Item{
+			Move: res.positions[i+1].LastMove,
+			Comment: pgn.Comment{
+				Depth: si.Depth,
+				Score: si.Score,
+				Time:  si.Time,
+			},
+		})
+	}
+	return pgn.WriteGame(w.file, tags, game, res.comment)
+}
@@ -13,6 +13,7 @@ func playGame(
 	ctx context.Context,
 	engineA, engineB IEngine,
 	tc TimeControl,
+	adjudication Adjudication,
 	info gameInfo,
 ) (gameResult, error) {
 
@@ -29,10 +30,24 @@ func playGame(
 
 	var positions []common.Position
 	positions = append(positions, startingPos)
+	var searchInfos []common.SearchInfo
 	var keys = make(map[uint64]int)
+	var clock = newClock(tc)
+	var adjudicator = newAdjudicator(adjudication)
 	var buf [common.MaxMoves]common.OrderedMove
 	var child common.Position
 
+	var finish = func(result int, comment, termination string) (gameResult, error) {
+		return gameResult{
+			gameInfo:    info,
+			positions:   positions,
+			searchInfos: searchInfos,
+			comment:     comment,
+			termination: termination,
+			result:      result,
+		}, nil
+	}
+
 	for {
 		var curPosition = &positions[len(positions)-1]
 		var ml = curPosition.GenerateMoves(buf[:])
@@ -54,20 +69,23 @@ func playGame(
 				} else {
 					points = gameResultWhiteWins
 				}
-				return gameResult{gameInfo: info, positions: positions, comment: "checkmate", result: points}, nil
+				return finish(points, "checkmate", terminationNormal)
 			} else {
-				return gameResult{gameInfo: info, positions: positions, comment: "stalemate", result: gameResultDraw}, nil
+				return finish(gameResultDraw, "stalemate", terminationNormal)
 			}
 		}
 		if curPosition.Rule50 >= 100 {
-			return gameResult{gameInfo: info, positions: positions, comment: "50 moves", result: gameResultDraw}, nil
+			return finish(gameResultDraw, "50 moves", terminationNormal)
 		}
 		if isLowMaterial(curPosition) {
-			return gameResult{gameInfo: info, positions: positions, comment: "low material", result: gameResultDraw}, nil
+			return finish(gameResultDraw, "low material", terminationNormal)
 		}
 		keys[curPosition.Key] += 1
 		if keys[curPosition.Key] == 3 {
-			return gameResult{gameInfo: info, positions: positions, comment: "3 fold repetition", result: gameResultDraw}, nil
+			return finish(gameResultDraw, "3 fold repetition", terminationNormal)
+		}
+		if result, comment, ok := adjudicator.adjudicate(curPosition); ok {
+			return finish(result, comment, terminationAdjudication)
 		}
 		var eng IEngine
 		if curPosition.WhiteMove == info.engineAIsWhite {
@@ -75,18 +93,17 @@ func playGame(
 		} else {
 			eng = engineB
 		}
-		var limits common.LimitsType
-		if tc.FixedNodes != 0 {
-			limits.Nodes = tc.FixedNodes
-		} else if tc.FixedTime != 0 {
-			limits.MoveTime = int(tc.FixedTime / time.Millisecond)
-		} else {
-			panic("bad time control")
+		var searchResult, elapsed = search(ctx, eng, positions, clock)
+		if ctx.Err() != nil {
+			return gameResult{}, ctx.Err()
+		}
+		if !clock.update(curPosition.WhiteMove, elapsed) {
+			var result, comment = timeLoss(curPosition, curPosition.WhiteMove)
+			return finish(result, comment, terminationTimeForfeit)
+		}
+		if len(searchResult.MainLine) == 0 {
+			return gameResult{}, engineError(eng)
 		}
-		var searchResult = eng.Search(context.Background(), common.SearchParams{
-			Positions: positions,
-			Limits:    limits,
-		})
 		var bestMove = searchResult.MainLine[0]
 		if !containsMove(ml, bestMove) {
 			return gameResult{}, fmt.Errorf("bad move")
@@ -94,10 +111,55 @@ func playGame(
 		if !curPosition.MakeMove(bestMove, &child) {
 			return gameResult{}, fmt.Errorf("bad move")
 		}
+		searchResult.Time = elapsed
+		searchInfos = append(searchInfos, searchResult)
+		adjudicator.onMove(curPosition, searchResult)
 		positions = append(positions, child)
 	}
 }
 
+func search(
+	ctx context.Context,
+	eng IEngine,
+	positions []common.Position,
+	clock *clock,
+) (common.SearchInfo, time.Duration) {
+	var whiteMove = positions[len(positions)-1].WhiteMove
+	var searchCtx = ctx
+	if deadline, ok := clock.deadline(whiteMove); ok {
+		var cancel context.CancelFunc
+		searchCtx, cancel = context.WithTimeout(ctx, deadline)
+		defer cancel()
+	}
+	var start = time.Now()
+	var searchResult = eng.Search(searchCtx, common.SearchParams{
+		Positions: positions,
+		Limits:    clock.limits(whiteMove),
+	})
+	return searchResult, time.Since(start)
+}
+
+func timeLoss(p *common.Position, whiteMove bool) (int, string) {
+	var winner = p.PiecesByColor(!whiteMove)
+	if winner&^p.Kings == 0 {
+		if whiteMove {
+			return gameResultDraw, "white loses on time, black has insufficient material"
+		}
+		return gameResultDraw, "black loses on time, white has insufficient material"
+	}
+	if whiteMove {
+		return gameResultBlackWins, "white loses on time"
+	}
+	return gameResultWhiteWins, "black loses on time"
+}
+
+func engineError(eng IEngine) error {
+	if e, ok := eng.(interface{ Err() error }); ok && e.Err() != nil {
+		return fmt.Errorf("engine failed: %w", e.Err())
+	}
+	return fmt.Errorf("engine returned no move")
+}
+
 func isLowMaterial(p *common.Position) bool {
 	if (p.Pawns|p.Rooks|p.Queens) == 0 &&
 		!common.MoreThanOne(p.Knights|p.Bishops) {
@@ -9,31 +9,103 @@ import (
 func showResults(
 	ctx context.Context,
 	gameResults <-chan gameResult,
+	resumed []gameResult,
+	pgnWriter *pgnWriter,
+	journal *journal,
+	sprt *sprt,
+	result *MatchResult,
 ) error {
 	//var totalGames = 2 * len(a.openings)
 	var games = 0
 	var wins, losses, draws int
-	for gameResult := range gameResults {
+	var addGame = func(gameResult gameResult) {
 		games++
-		//log.Printf("Finished game %v of %v: %v {%v}\n",
-		//	games, totalGames, gameResultString(gameResult.result), gameResult.comment)
-		log.Printf("Finished game %v: %v {%v}\n",
-			gameResult.gameInfo.gameNumber,
-			gameResultString(gameResult.result),
-			gameResult.comment)
+		var engineAScore float64
 		if gameResult.result == gameResultDraw {
 			draws++
+			engineAScore = 0.5
 		} else if gameResult.result == gameResultWhiteWins && gameResult.gameInfo.engineAIsWhite ||
 			gameResult.result == gameResultBlackWins && !gameResult.gameInfo.engineAIsWhite {
 			wins++
+			engineAScore = 1
 		} else {
 			losses++
 		}
+		*result = MatchResult{Wins: losses, Losses: wins, Draws: draws}
+		if sprt != nil {
+			sprt.addGame(pairIndex(gameResult.gameInfo.gameNumber), 1-engineAScore)
+		}
+	}
+	var showStat = func() error {
+		if games == 0 {
+			return nil
+		}
 		var stat = computeStat(wins, losses, draws)
 		log.Printf("Score: %v - %v - %v  [%.3f] %v\n",
 			wins, losses, draws, stat.winningFraction, games)
 		log.Printf("Elo difference: %.1f, LOS: %.1f %%\n",
 			stat.eloDifference, stat.los*100)
+		if sprt != nil {
+			var llr = sprt.llr()
+			log.Printf("SPRT experiment [%v, %v]: LLR %.2f [%.2f, %.2f] pentanomial %v\n",
+				sprt.config.Elo0, sprt.config.Elo1, llr, sprt.lower, sprt.upper, sprt.penta)
+			switch sprt.decision(llr) {
+			case 1:
+				log.Println("SPRT finished: H1 accepted")
+				return errSprtFinished
+			case -1:
+				log.Println("SPRT finished: H0 accepted")
+				return errSprtFinished
+			}
+		}
+		return nil
+	}
+
+	for _, gameResult := range resumed {
+		addGame(gameResult)
+	}
+	var err = showStat()
+	if err != nil {
+		return err
+	}
+
+	for gameResult := range gameResults {
+		//log.Printf("Finished game %v of %v: %v {%v}\n",
+		//	games, totalGames, gameResultString(gameResult.result), gameResult.comment)
+		log.Printf("Finished game %v: %v {%v}\n",
+			gameResult.gameInfo.gameNumber,
+			gameResultString(gameResult.result),
+			gameResult.comment)
+		if journal != nil {
+			var baseColour = colourBlack
+			if gameResult.gameInfo.engineAIsWhite {
+				baseColour = colourWhite
+			}
+			var err = journal.Append(journalEntry{
+				Round:      gameResult.gameInfo.gameNumber,
+				Opening:    pairIndex(gameResult.gameInfo.gameNumber),
+				Fen:        gameResult.gameInfo.opening,
+				White:      gameResult.whiteName,
+				Black:      gameResult.blackName,
+				BaseColour: baseColour,
+				Result:     gameResultString(gameResult.result),
+				Comment:    gameResult.comment,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if pgnWriter != nil {
+			var err = pgnWriter.Write(gameResult)
+			if err != nil {
+				return err
+			}
+		}
+		addGame(gameResult)
+		err = showStat()
+		if err != nil {
+			return err
+		}
 	}
 	return nil
 }
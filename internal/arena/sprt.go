@@ -0,0 +1,103 @@
+package arena
+
+import (
+	"errors"
+	"math"
+)
+
+var errSprtFinished = errors.New("sprt finished")
+
+// The variance estimate is unreliable on a handful of pairs, so no decision is made before.
+const sprtMinPairs = 10
+
+// SprtConfig describes a sequential probability ratio test of the experiment engine
+// against the base engine. H0: elo = Elo0, H1: elo = Elo1 (logistic elo).
+type SprtConfig struct {
+	Elo0  float64
+	Elo1  float64
+	Alpha float64
+	Beta  float64
+}
+
+type sprt struct {
+	config SprtConfig
+	lower  float64
+	upper  float64
+	// pentanomial frequencies of game pair scores 0, 0.5, 1, 1.5, 2
+	penta    [5]int
+	partials map[int]float64
+}
+
+func newSprt(config SprtConfig) *sprt {
+	return &sprt{
+		config:   config,
+		lower:    math.Log(config.Beta / (1 - config.Alpha)),
+		upper:    math.Log((1 - config.Beta) / config.Alpha),
+		partials: make(map[int]float64),
+	}
+}
+
+// addGame registers the experiment engine score of a game. Games with the same opening
+// are played with reversed colours and counted as a pair.
+func (s *sprt) addGame(pairIndex int, score float64) {
+	var first, found = s.partials[pairIndex]
+	if !found {
+		s.partials[pairIndex] = score
+		return
+	}
+	delete(s.partials, pairIndex)
+	s.penta[int(math.Round(2*(first+score)))]++
+}
+
+func (s *sprt) pairs() int {
+	var n int
+	for _, v := range s.penta {
+		n += v
+	}
+	return n
+}
+
+// llr computes the generalized SPRT log likelihood ratio for pentanomial game pair results.
+// See Michel Van den Bergh, "A practical introduction to the GSPRT".
+func (s *sprt) llr() float64 {
+	const prior = 1e-3
+	var total float64
+	var freq [5]float64
+	for i, v := range s.penta {
+		freq[i] = float64(v) + prior
+		total += freq[i]
+	}
+	var mean, variance float64
+	for i := range freq {
+		freq[i] /= total
+		mean += freq[i] * float64(i) / 4
+	}
+	for i := range freq {
+		var x = float64(i)/4 - mean
+		variance += freq[i] * x * x
+	}
+	if variance == 0 {
+		return 0
+	}
+	var s0 = eloToScore(s.config.Elo0)
+	var s1 = eloToScore(s.config.Elo1)
+	return float64(s.pairs()) * (s1 - s0) * (2*mean - s0 - s1) / (2 * variance)
+}
+
+// decision returns +1 if H1 accepted, -1 if H0 accepted, 0 if the test should continue.
+func (s *sprt) decision(llr float64) int {
+	if s.pairs() < sprtMinPairs {
+		return 0
+	}
+	if llr >= s.upper {
+		return 1
+	}
+	if llr <= s.lower {
+		return -1
+	}
+	return 0
+}
+
+func eloToScore(elo float64) float64 {
+	return 1 / (1 + math.Pow(10, -elo/400))
+}
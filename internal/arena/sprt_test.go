@@ -0,0 +1,60 @@
+package arena
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSprt(t *testing.T) {
+	var tests = []struct {
+		penta    [5]int
+		elo1     float64
+		llr      float64
+		decision int
+	}{
+		{[5]int{0, 10, 0, 10, 0}, 5, -0.0082810, 0},
+		{[5]int{0, 10, 0, 10, 0}, 10, -0.0331103, 0},
+		{[5]int{100, 300, 1000, 400, 200}, 5, 8.4158989, 1},
+		{[5]int{200, 400, 1000, 300, 100}, 5, -10.2020008, -1},
+		{[5]int{10, 40, 100, 45, 12}, 5, 0.2078063, 0},
+		{[5]int{2, 0, 5, 0, 3}, 10, 0.0502442, 0},
+	}
+	for _, test := range tests {
+		var s = newSprt(SprtConfig{Elo0: 0, Elo1: test.elo1, Alpha: 0.05, Beta: 0.05})
+		s.penta = test.penta
+		var llr = s.llr()
+		if math.Abs(llr-test.llr) > 1e-6 {
+			t.Errorf("%v elo1 %v: llr %v, expected %v", test.penta, test.elo1, llr, test.llr)
+		}
+		if decision := s.decision(llr); decision != test.decision {
+			t.Errorf("%v elo1 %v: decision %v, expected %v", test.penta, test.elo1, decision, test.decision)
+		}
+	}
+}
+
+func TestSprtBounds(t *testing.T) {
+	var s = newSprt(SprtConfig{Elo0: 0, Elo1: 5, Alpha: 0.05, Beta: 0.05})
+	if math.Abs(s.upper-math.Log(19)) > 1e-12 || math.Abs(s.lower+math.Log(19)) > 1e-12 {
+		t.Fatalf("bounds %v %v", s.lower, s.upper)
+	}
+	s.penta = [5]int{0, 0, 0, 0, sprtMinPairs - 1}
+	if decision := s.decision(s.upper); decision != 0 {
+		t.Fatalf("decision %v before %v pairs", decision, sprtMinPairs)
+	}
+	s.penta[4]++
+	if s.decision(s.upper) != 1 || s.decision(s.lower) != -1 || s.decision(0) != 0 {
+		t.Fatal("decision at bounds")
+	}
+}
+
+func TestSprtAddGame(t *testing.T) {
+	var s = newSprt(SprtConfig{Elo0: 0, Elo1: 5, Alpha: 0.05, Beta: 0.05})
+	s.addGame(0, 1)
+	s.addGame(1, 0)
+	s.addGame(0, 0.5)
+	s.addGame(2, 0.5)
+	s.addGame(2, 0.5)
+	if s.penta != [5]int{0, 0, 1, 1, 0} || len(s.partials) != 1 {
+		t.Fatalf("penta %v partials %v", s.penta, s.partials)
+	}
+}
@@ -0,0 +1,157 @@
+package arena
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ChizhovVadim/CounterGo/pkg/common"
+)
+
+// ParseTimeControl parses "nodes=N", "st=seconds" or cutechess style "[moves/]base[+inc]" in seconds.
+func ParseTimeControl(s string) (TimeControl, error) {
+	var err error
+	var tc TimeControl
+	if strings.HasPrefix(s, "nodes=") {
+		tc.FixedNodes, err = strconv.Atoi(strings.TrimPrefix(s, "nodes="))
+		if err != nil || tc.FixedNodes <= 0 {
+			return TimeControl{}, fmt.Errorf("bad time control %v", s)
+		}
+		return tc, nil
+	}
+	if strings.HasPrefix(s, "st=") {
+		tc.FixedTime, err = parseSeconds(strings.TrimPrefix(s, "st="))
+		if err != nil || tc.FixedTime <= 0 {
+			return TimeControl{}, fmt.Errorf("bad time control %v", s)
+		}
+		return tc, nil
+	}
+	var rest = s
+	if index := strings.Index(rest, "/"); index >= 0 {
+		tc.Moves, err = strconv.Atoi(rest[:index])
+		if err != nil || tc.Moves <= 0 {
+			return TimeControl{}, fmt.Errorf("bad time control %v", s)
+		}
+		rest = rest[index+1:]
+	}
+	if index := strings.Index(rest, "+"); index >= 0 {
+		tc.Increment, err = parseSeconds(rest[index+1:])
+		if err != nil {
+			return TimeControl{}, fmt.Errorf("bad time control %v", s)
+		}
+		rest = rest[:index]
+	}
+	tc.Base, err = parseSeconds(rest)
+	if err != nil || tc.Base <= 0 {
+		return TimeControl{}, fmt.Errorf("bad time control %v", s)
+	}
+	return tc, nil
+}
+
+func parseSeconds(s string) (time.Duration, error) {
+	var v, err = strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(v * float64(time.Second)), nil
+}
+
+func (tc TimeControl) isClock() bool {
+	return tc.Base != 0
+}
+
+type clock struct {
+	tc        TimeControl
+	remaining [common.COLOUR_NB]time.Duration
+	moves     [common.COLOUR_NB]int
+}
+
+func newClock(tc TimeControl) *clock {
+	return &clock{
+		tc:        tc,
+		remaining: [common.COLOUR_NB]time.Duration{tc.Base, tc.Base},
+	}
+}
+
+func (c *clock) limits(whiteMove bool) common.LimitsType {
+	var tc = c.tc
+	var limits common.LimitsType
+	if tc.FixedNodes != 0 {
+		limits.Nodes = tc.FixedNodes
+	} else if tc.FixedTime != 0 {
+		limits.MoveTime = int(tc.FixedTime / time.Millisecond)
+	} else if tc.isClock() {
+		limits.WhiteTime = int(c.remaining[common.SideWhite] / time.Millisecond)
+		limits.BlackTime = int(c.remaining[common.SideBlack] / time.Millisecond)
+		limits.WhiteIncrement = int(tc.Increment / time.Millisecond)
+		limits.BlackIncrement = int(tc.Increment / time.Millisecond)
+		if tc.Moves != 0 {
+			limits.MovesToGo = tc.Moves - c.moves[sideIndex(whiteMove)]%tc.Moves
+		}
+	} else {
+		panic("bad time control")
+	}
+	return limits
+}
+
+// deadline returns the time left for the side to move, including the tolerated overrun.
+func (c *clock) deadline(whiteMove bool) (time.Duration, bool) {
+	if !c.tc.isClock() {
+		return 0, false
+	}
+	return c.remaining[sideIndex(whiteMove)] + c.tc.Margin, true
+}
+
+// update returns false if the side to move has lost on time.
+func (c *clock) update(whiteMove bool, elapsed time.Duration) bool {
+	if !c.tc.isClock() {
+		return true
+	}
+	var side = sideIndex(whiteMove)
+	c.remaining[side] -= elapsed
+	if c.remaining[side] < -c.tc.Margin {
+		return false
+	}
+	if c.remaining[side] < 0 {
+		c.remaining[side] = 0
+	}
+	c.remaining[side] += c.tc.Increment
+	c.moves[side]++
+	if c.tc.Moves != 0 && c.moves[side]%c.tc.Moves == 0 {
+		c.remaining[side] += c.tc.Base
+	}
+	return true
+}
+
+func sideIndex(whiteMove bool) int {
+	if whiteMove {
+		return common.SideWhite
+	}
+	return common.SideBlack
+}
+
+// String returns the time control in the format accepted by ParseTimeControl.
+func (tc TimeControl) String() string {
+	if tc.FixedNodes != 0 {
+		return "nodes=" + strconv.Itoa(tc.FixedNodes)
+	}
+	if tc.FixedTime != 0 {
+		return "st=" + formatSeconds(tc.FixedTime)
+	}
+	if !tc.isClock() {
+		return "-"
+	}
+	var s = formatSeconds(tc.Base)
+	if tc.Moves != 0 {
+		s = strconv.Itoa(tc.Moves) + "/" + s
+	}
+	if tc.Increment != 0 {
+		s += "+" + formatSeconds(tc.Increment)
+	}
+	return s
+}
+
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
@@ -0,0 +1,84 @@
+package arena
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimeControl(t *testing.T) {
+	var tests = []struct {
+		s  string
+		tc TimeControl
+		ok bool
+	}{
+		{"nodes=2000000", TimeControl{FixedNodes: 2000000}, true},
+		{"st=0.5", TimeControl{FixedTime: 500 * time.Millisecond}, true},
+		{"60", TimeControl{Base: time.Minute}, true},
+		{"10+0.1", TimeControl{Base: 10 * time.Second, Increment: 100 * time.Millisecond}, true},
+		{"40/60", TimeControl{Base: time.Minute, Moves: 40}, true},
+		{"40/60+1", TimeControl{Base: time.Minute, Increment: time.Second, Moves: 40}, true},
+		{"nodes=0", TimeControl{}, false},
+		{"nodes=x", TimeControl{}, false},
+		{"st=-1", TimeControl{}, false},
+		{"0+1", TimeControl{}, false},
+		{"0/60", TimeControl{}, false},
+		{"10+x", TimeControl{}, false},
+		{"", TimeControl{}, false},
+	}
+	for _, test := range tests {
+		var tc, err = ParseTimeControl(test.s)
+		if (err == nil) != test.ok || tc != test.tc {
+			t.Errorf("%q: %+v %v, expected %+v", test.s, tc, err, test.tc)
+			continue
+		}
+		if test.ok && tc.String() != test.s {
+			t.Errorf("%q: String %q", test.s, tc.String())
+		}
+	}
+}
+
+func TestClockUpdate(t *testing.T) {
+	var tests = []struct {
+		name      string
+		tc        TimeControl
+		elapsed   []time.Duration // by white moves only
+		remaining time.Duration
+		ok        bool
+	}{
+		{"increment", TimeControl{Base: 10 * time.Second, Increment: time.Second},
+			[]time.Duration{3 * time.Second, 3 * time.Second}, 6 * time.Second, true},
+		{"time loss", TimeControl{Base: 10 * time.Second, Increment: time.Second},
+			[]time.Duration{11 * time.Second}, -time.Second, false},
+		{"margin", TimeControl{Base: 10 * time.Second, Increment: time.Second, Margin: 2 * time.Second},
+			[]time.Duration{11 * time.Second}, time.Second, true},
+		{"moves", TimeControl{Base: 10 * time.Second, Moves: 2},
+			[]time.Duration{4 * time.Second, 4 * time.Second}, 12 * time.Second, true},
+		{"fixed nodes", TimeControl{FixedNodes: 1000},
+			[]time.Duration{time.Hour}, 0, true},
+	}
+	for _, test := range tests {
+		var c = newClock(test.tc)
+		var ok = true
+		for _, elapsed := range test.elapsed {
+			ok = c.update(true, elapsed)
+		}
+		if ok != test.ok || c.remaining[sideIndex(true)] != test.remaining {
+			t.Errorf("%v: remaining %v ok %v, expected %v ok %v",
+				test.name, c.remaining[sideIndex(true)], ok, test.remaining, test.ok)
+		}
+		if c.remaining[sideIndex(false)] != test.tc.Base {
+			t.Errorf("%v: black remaining %v", test.name, c.remaining[sideIndex(false)])
+		}
+	}
+}
+
+func TestClockMovesToGo(t *testing.T) {
+	var c = newClock(TimeControl{Base: time.Minute, Moves: 40})
+	for i := 0; i < 41; i++ {
+		var expected = 40 - i%40
+		if movesToGo := c.limits(true).MovesToGo; movesToGo != expected {
+			t.Fatalf("move %v: moves to go %v, expected %v", i, movesToGo, expected)
+		}
+		c.update(true, time.Second)
+	}
+}
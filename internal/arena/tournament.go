@@ -0,0 +1,255 @@
+package arena
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+)
+
+type Player struct {
+	Name  string
+	Build func() (IEngine, error)
+}
+
+type TournamentConfig struct {
+	GameConcurrency int
+	TimeControl     TimeControl
+	Adjudication    Adjudication
+	Openings        Openings
+	Gauntlet        bool   // first player plays all others, otherwise round robin
+	PgnPath         string // optional
+	StatePath       string // optional, journal of finished games to resume from
+}
+
+type tournamentGame struct {
+	info    gameInfo
+	opening int
+	white   int
+	black   int
+}
+
+type tournamentResult struct {
+	game tournamentGame
+	res  gameResult
+}
+
+func RunTournament(
+	ctx context.Context,
+	config TournamentConfig,
+	players []Player,
+) error {
+	log.Println("tournament started")
+	defer log.Println("tournament finished")
+
+	if len(players) < 2 {
+		return fmt.Errorf("at least two players are expected")
+	}
+	var playerIndex = make(map[string]int)
+	for i, player := range players {
+		if _, found := playerIndex[player.Name]; found {
+			return fmt.Errorf("duplicate player name %v", player.Name)
+		}
+		playerIndex[player.Name] = i
+	}
+
+	openings, err := loadOpeningFens(config.Openings)
+	if err != nil {
+		return err
+	}
+	var schedule = buildSchedule(len(openings), len(players), config.Gauntlet)
+
+	var table = newCrosstable(players)
+	var finished = make(map[journalKey]struct{})
+	var journal *journal
+	if config.StatePath != "" {
+		var entries []journalEntry
+		journal, entries, err = openJournal(config.StatePath)
+		if err != nil {
+			return err
+		}
+		defer journal.Close()
+		for _, entry := range entries {
+			white, whiteOk := playerIndex[entry.White]
+			black, blackOk := playerIndex[entry.Black]
+			result, resultOk := parseGameResult(entry.Result)
+			if !(whiteOk && blackOk && resultOk) {
+				continue
+			}
+			finished[entry.key()] = struct{}{}
+			table.addGame(white, black, result)
+		}
+		log.Println("resumed games", len(finished))
+	}
+
+	var pgnWriter *pgnWriter
+	if config.PgnPath != "" {
+		pgnWriter, err = newPgnWriter(config.PgnPath, config.TimeControl)
+		if err != nil {
+			return err
+		}
+		defer pgnWriter.Close()
+	}
+
+	log.Println("players", len(players),
+		"openings", len(openings),
+		"games", len(schedule),
+		"gameConcurrency", config.GameConcurrency)
+	log.Printf("%+v\n", config.TimeControl)
+
+	g, ctx := errgroup.WithContext(ctx)
+
+	var games = make(chan tournamentGame)
+	var results = make(chan tournamentResult)
+
+	g.Go(func() error {
+		defer close(games)
+		for _, game := range schedule {
+			var key = journalKey{opening: game.opening, white: players[game.white].Name, black: players[game.black].Name}
+			if _, found := finished[key]; found {
+				continue
+			}
+			game.info.opening = openings[game.opening]
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case games <- game:
+			}
+		}
+		return nil
+	})
+
+	g.Go(func() error {
+		for result := range results {
+			var game = result.game
+			var res = result.res
+			log.Printf("Finished game %v: %v - %v %v {%v}\n",
+				game.info.gameNumber, res.whiteName, res.blackName,
+				gameResultString(res.result), res.comment)
+			if journal != nil {
+				var err = journal.Append(journalEntry{
+					Round:   game.info.gameNumber,
+					Opening: game.opening,
+					White:   res.whiteName,
+					Black:   res.blackName,
+					Result:  gameResultString(res.result),
+					Comment: res.comment,
+				})
+				if err != nil {
+					return err
+				}
+			}
+			if pgnWriter != nil {
+				var err = pgnWriter.Write(res)
+				if err != nil {
+					return err
+				}
+			}
+			table.addGame(game.white, game.black, res.result)
+			if table.totalGames()%10 == 0 {
+				table.print()
+			}
+		}
+		table.print()
+		return nil
+	})
+
+	var wg = &sync.WaitGroup{}
+	for i := 0; i < config.GameConcurrency; i++ {
+		wg.Add(1)
+		g.Go(func() error {
+			defer wg.Done()
+			var engines = make(map[int]IEngine)
+			defer func() {
+				for _, eng := range engines {
+					closeEngine(eng)
+				}
+			}()
+			var getEngine = func(index int) (IEngine, error) {
+				if eng, found := engines[index]; found {
+					return eng, nil
+				}
+				var eng, err = players[index].Build()
+				if err != nil {
+					return nil, err
+				}
+				engines[index] = eng
+				return eng, nil
+			}
+			for game := range games {
+				white, err := getEngine(game.white)
+				if err != nil {
+					return err
+				}
+				black, err := getEngine(game.black)
+				if err != nil {
+					return err
+				}
+				res, err := playGame(ctx, white, black, config.TimeControl, config.Adjudication, game.info)
+				if err != nil {
+					return err
+				}
+				res.whiteName = players[game.white].Name
+				res.blackName = players[game.black].Name
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case results <- tournamentResult{game: game, res: res}:
+				}
+			}
+			return nil
+		})
+	}
+
+	g.Go(func() error {
+		wg.Wait()
+		close(results)
+		return nil
+	})
+
+	return g.Wait()
+}
+
+// buildSchedule returns games ordered by opening, each pairing plays the opening with both colours.
+func buildSchedule(openings, players int, gauntlet bool) []tournamentGame {
+	var pairings [][2]int
+	for i := 0; i < players; i++ {
+		for j := i + 1; j < players; j++ {
+			if gauntlet && i != 0 {
+				continue
+			}
+			pairings = append(pairings, [2]int{i, j})
+		}
+	}
+	var result []tournamentGame
+	for opening := 0; opening < openings; opening++ {
+		for _, pairing := range pairings {
+			for _, colours := range [][2]int{{pairing[0], pairing[1]}, {pairing[1], pairing[0]}} {
+				result = append(result, tournamentGame{
+					info: gameInfo{
+						engineAIsWhite: true,
+						gameNumber:     len(result) + 1,
+					},
+					opening: opening,
+					white:   colours[0],
+					black:   colours[1],
+				})
+			}
+		}
+	}
+	return result
+}
+
+func parseGameResult(s string) (int, bool) {
+	switch s {
+	case "1-0":
+		return gameResultWhiteWins, true
+	case "0-1":
+		return gameResultBlackWins, true
+	case "1/2-1/2":
+		return gameResultDraw, true
+	}
+	return 0, false
+}
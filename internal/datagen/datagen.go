@@ -0,0 +1,271 @@
+package datagen
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/ChizhovVadim/CounterGo/internal/arena"
+	"github.com/ChizhovVadim/CounterGo/internal/dataset"
+	"github.com/ChizhovVadim/CounterGo/internal/opengen"
+	"github.com/ChizhovVadim/CounterGo/internal/pgn"
+	"github.com/ChizhovVadim/CounterGo/pkg/common"
+)
+
+type Config struct {
+	Games       int
+	Nodes       int
+	Concurrency int
+	Seed        int64  // game i uses seed Seed+i, so output does not depend on concurrency
+	BookPath    string // optional EPD or PGN opening file
+	RandomPlies int    // random moves played after the book position
+	ResignScore int    // game is adjudicated when the score is beyond ResignScore for ResignPlies plies, 0 disables
+	ResignPlies int
+	MaxPlies    int // game is drawn after MaxPlies plies, 0 disables
+	OutputPath  string
+	Binary      bool // binary dataset instead of annotated PGN
+}
+
+type IEngine interface {
+	Clear()
+	Search(ctx context.Context, searchParams common.SearchParams) common.SearchInfo
+}
+
+type gameTask struct {
+	index int
+	start common.Position
+}
+
+type gameRecord struct {
+	index int
+	game  pgn.Game
+}
+
+func Run(
+	ctx context.Context,
+	config Config,
+	engineBuilder func() IEngine,
+) error {
+	log.Println("datagen started")
+	defer log.Println("datagen finished")
+	log.Printf("%+v\n", config)
+
+	var book []string
+	if config.BookPath != "" {
+		var err error
+		book, err = arena.LoadOpeningFile(config.BookPath)
+		if err != nil {
+			return err
+		}
+		if len(book) == 0 {
+			return fmt.Errorf("no openings in %v", config.BookPath)
+		}
+	}
+	startPosition, err := common.NewPositionFromFEN(common.InitialPositionFen)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(config.OutputPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	g, ctx := errgroup.WithContext(ctx)
+
+	var tasks = make(chan gameTask)
+	var records = make(chan gameRecord)
+
+	g.Go(func() error {
+		defer close(tasks)
+		for i := 0; i < config.Games; i++ {
+			var start, err = gameStart(config, book, startPosition, i)
+			if err != nil {
+				return err
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case tasks <- gameTask{index: i, start: start}:
+			}
+		}
+		return nil
+	})
+
+	var wg = &sync.WaitGroup{}
+	for i := 0; i < config.Concurrency; i++ {
+		wg.Add(1)
+		g.Go(func() error {
+			defer wg.Done()
+			var eng = engineBuilder()
+			for task := range tasks {
+				var game, err = playGame(ctx, eng, config, task.start)
+				if err != nil {
+					return err
+				}
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case records <- gameRecord{index: task.index, game: game}:
+				}
+			}
+			return nil
+		})
+	}
+
+	g.Go(func() error {
+		wg.Wait()
+		close(records)
+		return nil
+	})
+
+	var writer gameWriter
+	if config.Binary {
+		writer = &packedGameWriter{w: dataset.NewPackedWriter(file)}
+	} else {
+		writer = &pgnGameWriter{w: file}
+	}
+	g.Go(func() error {
+		return saveGames(records, writer)
+	})
+
+	err = g.Wait()
+	if err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+// maxStartDraws limits openings drawn for one game, every book line failing is a config error.
+const maxStartDraws = 100
+
+// gameStart draws opening of game index. A book line without balanced random continuation
+// is replaced by the next draw, its seed is derived from the game seed, so output stays deterministic.
+func gameStart(config Config, book []string, startPosition common.Position, index int) (common.Position, error) {
+	var err error
+	for draw := 0; draw < maxStartDraws; draw++ {
+		var r = rand.New(rand.NewSource(config.Seed + int64(index) + int64(draw)<<32))
+		var start = startPosition
+		if len(book) != 0 {
+			var fen = book[r.Intn(len(book))]
+			start, err = common.NewPositionFromFEN(fen)
+			if err != nil {
+				err = fmt.Errorf("book line %v: %w", fen, err)
+				log.Println("game", index+1, err)
+				continue
+			}
+		}
+		start, err = opengen.RandomOpening(r, start, config.RandomPlies)
+		if err == nil {
+			return start, nil
+		}
+		log.Println("game", index+1, err)
+	}
+	return common.Position{}, fmt.Errorf("game %v: no opening in %v draws: %w", index+1, maxStartDraws, err)
+}
+
+// saveGames writes games in index order.
+func saveGames(records <-chan gameRecord, writer gameWriter) error {
+	var start = time.Now()
+	var lastLog = start
+	var pending = make(map[int]pgn.Game)
+	var next, positions int
+	for record := range records {
+		pending[record.index] = record.game
+		for {
+			var game, found = pending[next]
+			if !found {
+				break
+			}
+			delete(pending, next)
+			var err = writer.Write(next, game)
+			if err != nil {
+				return err
+			}
+			next++
+			positions += len(game.Items)
+		}
+		if time.Since(lastLog) >= 10*time.Second {
+			lastLog = time.Now()
+			logProgress(next, positions, start)
+		}
+	}
+	logProgress(next, positions, start)
+	return writer.Flush()
+}
+
+type gameWriter interface {
+	Write(index int, game pgn.Game) error
+	Flush() error
+}
+
+type pgnGameWriter struct {
+	w io.Writer
+}
+
+func (gw *pgnGameWriter) Write(index int, game pgn.Game) error {
+	return pgn.WriteGame(gw.w, gameTags(index, game), game, "")
+}
+
+func (gw *pgnGameWriter) Flush() error {
+	return nil
+}
+
+// packedGameWriter writes all scored positions, the dataset loaders filter them.
+type packedGameWriter struct {
+	w *dataset.PackedWriter
+}
+
+func (gw *packedGameWriter) Write(index int, game pgn.Game) error {
+	var info, err = dataset.AnalyzeParsedGame(game)
+	if err != nil {
+		return err
+	}
+	positions, err := dataset.PackGame(&info, nil)
+	if err != nil {
+		return err
+	}
+	for i := range positions {
+		err = gw.w.Write(&positions[i])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (gw *packedGameWriter) Flush() error {
+	return gw.w.Flush()
+}
+
+func logProgress(games, positions int, start time.Time) {
+	var elapsed = time.Since(start)
+	log.Println("games", games,
+		"positions", positions,
+		"positions/s", int(float64(positions)/elapsed.Seconds()))
+}
+
+func gameTags(index int, game pgn.Game) []pgn.Tag {
+	var tags = []pgn.Tag{
+		{Key: "Event", Value: "datagen"},
+		{Key: "Round", Value: strconv.Itoa(index + 1)},
+		{Key: "White", Value: "Counter"},
+		{Key: "Black", Value: "Counter"},
+		{Key: "Result", Value: game.Result},
+	}
+	if game.Fen != common.InitialPositionFen {
+		tags = append(tags,
+			pgn.Tag{Key: "FEN", Value: game.Fen},
+			pgn.Tag{Key: "SetUp", Value: "1"})
+	}
+	return tags
+}
@@ -0,0 +1,62 @@
+package datagen
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ChizhovVadim/CounterGo/pkg/common"
+	"github.com/ChizhovVadim/CounterGo/pkg/engine"
+	counter "github.com/ChizhovVadim/CounterGo/pkg/eval/counter"
+)
+
+func TestRunDeterministic(t *testing.T) {
+	var dir = t.TempDir()
+	// the mated book line has no random continuation, its games draw another line
+	var book = strings.Join([]string{
+		"rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq -",
+		common.InitialPositionFen,
+	}, "\n")
+	var bookPath = filepath.Join(dir, "book.epd")
+	var err = os.WriteFile(bookPath, []byte(book), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var run = func(concurrency int) []byte {
+		var config = Config{
+			Games:       6,
+			Nodes:       1000,
+			Concurrency: concurrency,
+			Seed:        1,
+			BookPath:    bookPath,
+			RandomPlies: 4,
+			MaxPlies:    30,
+			OutputPath:  filepath.Join(dir, "games.pgn"),
+		}
+		var err = Run(context.Background(), config, func() IEngine {
+			var eng = engine.NewEngine(engine.NewMainOptions(func() interface{} {
+				return counter.NewEvaluationService()
+			}))
+			eng.Prepare()
+			return eng
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(config.OutputPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	var single = run(1)
+	if games := bytes.Count(single, []byte("[Event ")); games != 6 {
+		t.Fatalf("%v games, expected 6", games)
+	}
+	if !bytes.Equal(single, run(3)) {
+		t.Fatal("output depends on concurrency")
+	}
+}
@@ -0,0 +1,129 @@
+package datagen
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ChizhovVadim/CounterGo/internal/pgn"
+	"github.com/ChizhovVadim/CounterGo/pkg/common"
+)
+
+// playGame plays a self-play game and records the search score and depth of every move.
+func playGame(
+	ctx context.Context,
+	eng IEngine,
+	config Config,
+	start common.Position,
+) (pgn.Game, error) {
+	eng.Clear()
+
+	var positions = []common.Position{start}
+	var items []pgn.Item
+	var keys = make(map[uint64]int)
+	var whiteWinPlies, blackWinPlies int
+	var buf [common.MaxMoves]common.OrderedMove
+	var child common.Position
+
+	var finish = func(result string) (pgn.Game, error) {
+		return pgn.Game{
+			Result: result,
+			Fen:    start.String(),
+			Items:  items,
+		}, nil
+	}
+	var winner = func(whiteWins bool) string {
+		if whiteWins {
+			return pgn.GameResultWhiteWin
+		}
+		return pgn.GameResultBlackWin
+	}
+
+	for {
+		var curPosition = &positions[len(positions)-1]
+		var ml = curPosition.GenerateMoves(buf[:])
+
+		var hasLegalMove = false
+		for i := range ml {
+			if curPosition.MakeMove(ml[i].Move, &child) {
+				hasLegalMove = true
+				break
+			}
+		}
+		if !hasLegalMove {
+			if curPosition.IsCheck() {
+				return finish(winner(!curPosition.WhiteMove))
+			}
+			return finish(pgn.GameResultDraw)
+		}
+		if curPosition.Rule50 >= 100 || isLowMaterial(curPosition) {
+			return finish(pgn.GameResultDraw)
+		}
+		keys[curPosition.Key]++
+		if keys[curPosition.Key] == 3 {
+			return finish(pgn.GameResultDraw)
+		}
+		if config.MaxPlies != 0 && len(items) >= config.MaxPlies {
+			return finish(pgn.GameResultDraw)
+		}
+
+		var si = eng.Search(ctx, common.SearchParams{
+			Positions: positions,
+			Limits:    common.LimitsType{Nodes: config.Nodes},
+		})
+		if ctx.Err() != nil {
+			return pgn.Game{}, ctx.Err()
+		}
+		if len(si.MainLine) == 0 {
+			return pgn.Game{}, fmt.Errorf("engine returned no move")
+		}
+		var bestMove = si.MainLine[0]
+		if !curPosition.MakeMove(bestMove, &child) {
+			return pgn.Game{}, fmt.Errorf("bad move %v", bestMove)
+		}
+		items = append(items, pgn.Item{
+			Move: bestMove,
+			Comment: pgn.Comment{
+				Depth: si.Depth,
+				Score: si.Score,
+			},
+		})
+		positions = append(positions, child)
+
+		if config.ResignScore != 0 {
+			// consecutive plies of both sides agree on the winner
+			var whiteScore = scoreCentipawns(si.Score)
+			if !curPosition.WhiteMove {
+				whiteScore = -whiteScore
+			}
+			if whiteScore >= config.ResignScore {
+				whiteWinPlies, blackWinPlies = whiteWinPlies+1, 0
+			} else if whiteScore <= -config.ResignScore {
+				whiteWinPlies, blackWinPlies = 0, blackWinPlies+1
+			} else {
+				whiteWinPlies, blackWinPlies = 0, 0
+			}
+			if whiteWinPlies >= config.ResignPlies {
+				return finish(pgn.GameResultWhiteWin)
+			}
+			if blackWinPlies >= config.ResignPlies {
+				return finish(pgn.GameResultBlackWin)
+			}
+		}
+	}
+}
+
+func scoreCentipawns(score common.UciScore) int {
+	const mateScore = 30_000
+	if score.Mate > 0 {
+		return mateScore
+	}
+	if score.Mate < 0 {
+		return -mateScore
+	}
+	return score.Centipawns
+}
+
+func isLowMaterial(p *common.Position) bool {
+	return (p.Pawns|p.Rooks|p.Queens) == 0 &&
+		!common.MoreThanOne(p.Knights|p.Bishops)
+}
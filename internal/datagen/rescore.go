@@ -0,0 +1,194 @@
+package datagen
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/ChizhovVadim/CounterGo/internal/dataset"
+	"github.com/ChizhovVadim/CounterGo/pkg/common"
+)
+
+type RescoreConfig struct {
+	InputPath   string // binary dataset file
+	OutputPath  string
+	Depth       int
+	Nodes       int
+	Concurrency int
+}
+
+type rescoreChunk struct {
+	index     int
+	positions []dataset.PackedPosition
+}
+
+// Rescore searches every position of a binary dataset file and replaces its score and depth.
+// Game result and ply are kept. The output has the same order as the input.
+func Rescore(
+	ctx context.Context,
+	config RescoreConfig,
+	engineBuilder func() IEngine,
+) error {
+	log.Println("rescore started")
+	defer log.Println("rescore finished")
+	log.Printf("%+v\n", config)
+
+	if config.Depth == 0 && config.Nodes == 0 {
+		return fmt.Errorf("depth or nodes limit is required")
+	}
+	var limits = common.LimitsType{Depth: config.Depth, Nodes: config.Nodes}
+
+	inputInfo, err := os.Stat(config.InputPath)
+	if err != nil {
+		return err
+	}
+	// creating output would truncate input before it is read
+	if outputInfo, err := os.Stat(config.OutputPath); err == nil && os.SameFile(inputInfo, outputInfo) {
+		return fmt.Errorf("rescore output %v is the input file", config.OutputPath)
+	}
+
+	file, err := os.Create(config.OutputPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	g, ctx := errgroup.WithContext(ctx)
+
+	var inputs = make(chan rescoreChunk, config.Concurrency)
+	var outputs = make(chan rescoreChunk, config.Concurrency)
+
+	g.Go(func() error {
+		defer close(inputs)
+		const chunkSize = 256
+		var chunk rescoreChunk
+		var send = func() error {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case inputs <- chunk:
+				chunk = rescoreChunk{index: chunk.index + 1}
+				return nil
+			}
+		}
+		var err = dataset.WalkPackedFile(config.InputPath, func(pp *dataset.PackedPosition) error {
+			chunk.positions = append(chunk.positions, *pp)
+			if len(chunk.positions) == chunkSize {
+				return send()
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if len(chunk.positions) != 0 {
+			return send()
+		}
+		return nil
+	})
+
+	var wg = &sync.WaitGroup{}
+	for i := 0; i < config.Concurrency; i++ {
+		wg.Add(1)
+		g.Go(func() error {
+			defer wg.Done()
+			var eng = engineBuilder()
+			eng.Clear()
+			for chunk := range inputs {
+				for j := range chunk.positions {
+					var err = rescorePosition(ctx, eng, limits, &chunk.positions[j])
+					if err != nil {
+						return err
+					}
+				}
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case outputs <- chunk:
+				}
+			}
+			return nil
+		})
+	}
+
+	g.Go(func() error {
+		wg.Wait()
+		close(outputs)
+		return nil
+	})
+
+	g.Go(func() error {
+		var writer = dataset.NewPackedWriter(file)
+		var start = time.Now()
+		var lastLog = start
+		var pending = make(map[int][]dataset.PackedPosition)
+		var next int
+		for chunk := range outputs {
+			pending[chunk.index] = chunk.positions
+			for {
+				var positions, found = pending[next]
+				if !found {
+					break
+				}
+				delete(pending, next)
+				next++
+				for i := range positions {
+					var err = writer.Write(&positions[i])
+					if err != nil {
+						return err
+					}
+				}
+			}
+			if time.Since(lastLog) >= 10*time.Second {
+				lastLog = time.Now()
+				logRescoreProgress(writer.Count, start)
+			}
+		}
+		logRescoreProgress(writer.Count, start)
+		return writer.Flush()
+	})
+
+	err = g.Wait()
+	if err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+func rescorePosition(
+	ctx context.Context,
+	eng IEngine,
+	limits common.LimitsType,
+	pp *dataset.PackedPosition,
+) error {
+	var info, gameResult, err = pp.Unpack()
+	if err != nil {
+		return err
+	}
+	var si = eng.Search(ctx, common.SearchParams{
+		Positions: []common.Position{info.Position},
+		Limits:    limits,
+	})
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if len(si.MainLine) == 0 {
+		// no legal moves, the old score is kept
+		return nil
+	}
+	info.ScoreMate = si.Score.Mate
+	info.ScoreCentipawns = si.Score.Centipawns
+	info.Depth = si.Depth
+	*pp, err = dataset.PackPosition(&info, gameResult)
+	return err
+}
+
+func logRescoreProgress(positions int, start time.Time) {
+	log.Println("positions", positions,
+		"positions/s", int(float64(positions)/time.Since(start).Seconds()))
+}
@@ -16,6 +16,9 @@ type PositionInfo struct {
 	ScoreMate       int
 	ScoreCentipawns int
 	Position        common.Position
+	Ply             int // ply from the start of the game record
+	Depth           int
+	Move            common.Move // best move found by search, MoveEmpty if unknown
 }
 
 func AnalyzeGame(gameRaw pgn.GameRaw) (GameInfo, error) {
@@ -23,6 +26,10 @@ func AnalyzeGame(gameRaw pgn.GameRaw) (GameInfo, error) {
 	if err != nil {
 		return GameInfo{}, err
 	}
+	return AnalyzeParsedGame(game)
+}
+
+func AnalyzeParsedGame(game pgn.Game) (GameInfo, error) {
 	var gameResult, gameResOk = calcGameResult(game.Result)
 	if !gameResOk {
 		return GameInfo{}, fmt.Errorf("bad game result %v", game.Result)
@@ -44,14 +51,16 @@ func AnalyzeGame(gameRaw pgn.GameRaw) (GameInfo, error) {
 		var comment = game.Items[i].Comment
 
 		_, found := repeatPositions[pos.Key]
-		//filter repeats and noisy positions
-		if !(found ||
-			ignorePos(&pos, move, comment)) {
+		//positions without search score and repeats are not samples, the rest is up to Filter
+		if !(found || comment.Depth == 0) {
 
 			posInfos = append(posInfos, PositionInfo{
 				ScoreMate:       comment.Score.Mate,
 				ScoreCentipawns: comment.Score.Centipawns,
 				Position:        pos,
+				Ply:             i,
+				Depth:           comment.Depth,
+				Move:            move,
 			})
 		}
 
@@ -83,14 +92,3 @@ func calcGameResult(sGameResult string) (float64, bool) {
 		return 0, false
 	}
 }
-
-func ignorePos(
-	pos *common.Position,
-	searchBestMove common.Move,
-	searchComment pgn.Comment,
-) bool {
-	return searchComment.Depth == 0 ||
-		searchComment.Score.Mate != 0 ||
-		pos.IsCheck() ||
-		searchBestMove.CapturedPiece() != common.Empty
-}
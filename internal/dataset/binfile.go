@@ -0,0 +1,196 @@
+package dataset
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+)
+
+const packedExt = ".bin"
+
+// PackedWriter writes position records to a binary dataset file.
+type PackedWriter struct {
+	w     *bufio.Writer
+	Count int
+}
+
+func NewPackedWriter(w io.Writer) *PackedWriter {
+	return &PackedWriter{w: bufio.NewWriterSize(w, 1<<20)}
+}
+
+func (pw *PackedWriter) Write(pp *PackedPosition) error {
+	var _, err = pw.w.Write(pp[:])
+	if err == nil {
+		pw.Count++
+	}
+	return err
+}
+
+func (pw *PackedWriter) Flush() error {
+	return pw.w.Flush()
+}
+
+// WalkPackedFile streams records of a binary dataset file, so files larger than memory can be read.
+func WalkPackedFile(
+	path string,
+	onPosition func(pp *PackedPosition) error,
+) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	var reader = bufio.NewReaderSize(file, 1<<20)
+	var pp PackedPosition
+	for {
+		_, err = io.ReadFull(reader, pp[:])
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				return fmt.Errorf("%v: truncated record", path)
+			}
+			return err
+		}
+		err = onPosition(&pp)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// IsPackedDataset reports whether path is a binary dataset file.
+func IsPackedDataset(path string) bool {
+	return filepath.Ext(path) == packedExt
+}
+
+// LoadPackedPositions sends chunks of decoded positions, the counterpart of LoadGames for binary files.
+func LoadPackedPositions(
+	ctx context.Context,
+	path string,
+	datasetReady <-chan struct{},
+	positions chan<- []PackedPosition,
+) error {
+	const chunkSize = 1024
+	var errDatasetReady = errors.New("dataset ready")
+	var chunk []PackedPosition
+	var send = func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-datasetReady:
+			return errDatasetReady
+		case positions <- chunk:
+			chunk = nil
+			return nil
+		}
+	}
+	log.Println("loadPackedPositions",
+		"filepath", path)
+	var err = WalkPackedFile(path, func(pp *PackedPosition) error {
+		chunk = append(chunk, *pp)
+		if len(chunk) == chunkSize {
+			return send()
+		}
+		return nil
+	})
+	if err == nil {
+		err = send()
+	}
+	if errors.Is(err, errDatasetReady) {
+		return nil
+	}
+	return err
+}
+
+// ShufflePackedFile shuffles a binary dataset file on disk.
+// Records are scattered to random bucket files of about bucketSize records,
+// then every bucket is shuffled in memory and appended to the output.
+func ShufflePackedFile(inputPath, outputPath string, bucketSize int, seed int64) error {
+	info, err := os.Stat(inputPath)
+	if err != nil {
+		return err
+	}
+	var total = int(info.Size() / PackedPositionSize)
+	var bucketCount = 1 + total/bucketSize
+	log.Println("shuffle",
+		"positions", total,
+		"buckets", bucketCount)
+
+	tempDir, err := os.MkdirTemp(filepath.Dir(outputPath), "shuffle")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tempDir)
+
+	var rnd = rand.New(rand.NewSource(seed))
+	var bucketFiles = make([]*os.File, bucketCount)
+	var bucketWriters = make([]*PackedWriter, bucketCount)
+	defer func() {
+		for _, f := range bucketFiles {
+			if f != nil {
+				f.Close()
+			}
+		}
+	}()
+	for i := range bucketFiles {
+		bucketFiles[i], err = os.Create(filepath.Join(tempDir, fmt.Sprintf("%v%v", i, packedExt)))
+		if err != nil {
+			return err
+		}
+		bucketWriters[i] = &PackedWriter{w: bufio.NewWriterSize(bucketFiles[i], 1<<16)}
+	}
+	err = WalkPackedFile(inputPath, func(pp *PackedPosition) error {
+		return bucketWriters[rnd.Intn(bucketCount)].Write(pp)
+	})
+	if err != nil {
+		return err
+	}
+	for _, bw := range bucketWriters {
+		err = bw.Flush()
+		if err != nil {
+			return err
+		}
+	}
+
+	output, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer output.Close()
+	var writer = NewPackedWriter(output)
+	for i := range bucketFiles {
+		var bucket []PackedPosition
+		err = WalkPackedFile(bucketFiles[i].Name(), func(pp *PackedPosition) error {
+			bucket = append(bucket, *pp)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		rnd.Shuffle(len(bucket), func(i, j int) {
+			bucket[i], bucket[j] = bucket[j], bucket[i]
+		})
+		for j := range bucket {
+			err = writer.Write(&bucket[j])
+			if err != nil {
+				return err
+			}
+		}
+	}
+	err = writer.Flush()
+	if err != nil {
+		return err
+	}
+	return output.Sync()
+}
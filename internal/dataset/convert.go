@@ -0,0 +1,108 @@
+package dataset
+
+import (
+	"context"
+	"log"
+	"os"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/ChizhovVadim/CounterGo/internal/pgn"
+)
+
+// ConvertPgnToPacked writes the positions of all PGN files in gamesFolder to a binary dataset file.
+// Best moves are not stored, so filters that need them are applied here.
+func ConvertPgnToPacked(
+	ctx context.Context,
+	gamesFolder string,
+	outputPath string,
+	filter *Filter,
+	concurrency int,
+) error {
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	g, ctx := errgroup.WithContext(ctx)
+
+	var games = make(chan pgn.GameRaw, 16)
+	var chunks = make(chan []PackedPosition, 16)
+
+	g.Go(func() error {
+		defer close(games)
+		return LoadGames(ctx, gamesFolder, nil, games)
+	})
+
+	var wg = &sync.WaitGroup{}
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		g.Go(func() error {
+			defer wg.Done()
+			for gameRaw := range games {
+				var game, err = AnalyzeGame(gameRaw)
+				if err != nil {
+					return err
+				}
+				chunk, err := PackGame(&game, filter)
+				if err != nil {
+					return err
+				}
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case chunks <- chunk:
+				}
+			}
+			return nil
+		})
+	}
+
+	g.Go(func() error {
+		wg.Wait()
+		close(chunks)
+		return nil
+	})
+
+	var writer = NewPackedWriter(file)
+	g.Go(func() error {
+		for chunk := range chunks {
+			for i := range chunk {
+				var err = writer.Write(&chunk[i])
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return writer.Flush()
+	})
+
+	err = g.Wait()
+	if err != nil {
+		return err
+	}
+	log.Println("convert",
+		"positions", writer.Count)
+	if filter != nil {
+		filter.LogStats()
+	}
+	return file.Sync()
+}
+
+// PackGame encodes the positions of the game that pass the optional filter.
+func PackGame(game *GameInfo, filter *Filter) ([]PackedPosition, error) {
+	var result = make([]PackedPosition, 0, len(game.Positions))
+	for i := range game.Positions {
+		if filter != nil && !filter.Accept(&game.Positions[i]) {
+			continue
+		}
+		var pp, err = PackPosition(&game.Positions[i], game.GameResult)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, pp)
+	}
+	return result, nil
+}
@@ -0,0 +1,180 @@
+package dataset
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ChizhovVadim/CounterGo/pkg/common"
+	"github.com/ChizhovVadim/CounterGo/pkg/engine"
+)
+
+// FilterConfig selects the positions used for training. Zero values disable the corresponding rule.
+type FilterConfig struct {
+	MinPly           int
+	MaxPly           int
+	MaxScore         int  // maximum absolute score in centipawns
+	SkipMate         bool // skip mate scores
+	SkipCheck        bool
+	SkipCapture      bool // skip positions where the best move is a capture, only known for PGN games
+	QuietSee         bool // skip positions with a winning capture by SEE, i.e. not quiet for qsearch
+	MinPieces        int
+	MaxPieces        int
+	MaxPerPieceCount int  // maximum positions per piece count bucket
+	Dedup            bool // skip positions seen before in any file, by Zobrist key
+}
+
+// DefaultFilterConfig keeps positions that the dataset loaders historically used.
+func DefaultFilterConfig() FilterConfig {
+	return FilterConfig{
+		SkipMate:    true,
+		SkipCheck:   true,
+		SkipCapture: true,
+	}
+}
+
+const (
+	filterAccepted = iota
+	filterPly
+	filterScore
+	filterMate
+	filterCheck
+	filterCapture
+	filterQuiet
+	filterPieces
+	filterDuplicate
+	filterBucket
+	filterReasonCount
+)
+
+var filterReasonNames = [filterReasonCount]string{
+	"accepted", "ply", "score", "mate", "check", "capture", "quiet", "pieces", "duplicate", "bucket",
+}
+
+// Filter is a position filter chain shared by the train and tuner loaders. It is safe for concurrent use.
+type Filter struct {
+	config  FilterConfig
+	mu      sync.Mutex
+	seen    map[uint64]struct{} // nil after HoldSeen
+	held    map[uint64]struct{}
+	buckets [33]int64
+	stats   [filterReasonCount]int64
+}
+
+func NewFilter(config FilterConfig) *Filter {
+	return &Filter{
+		config: config,
+		seen:   make(map[uint64]struct{}),
+	}
+}
+
+// Accept reports whether the position passes the filter chain and counts the reason if not.
+func (f *Filter) Accept(info *PositionInfo) bool {
+	var reason = f.check(info)
+	atomic.AddInt64(&f.stats[reason], 1)
+	return reason == filterAccepted
+}
+
+func (f *Filter) check(info *PositionInfo) int {
+	var config = &f.config
+	var pos = &info.Position
+	if info.Ply < config.MinPly ||
+		config.MaxPly != 0 && info.Ply > config.MaxPly {
+		return filterPly
+	}
+	if config.SkipMate && info.ScoreMate != 0 {
+		return filterMate
+	}
+	if config.MaxScore != 0 && info.ScoreMate == 0 &&
+		(info.ScoreCentipawns > config.MaxScore || info.ScoreCentipawns < -config.MaxScore) {
+		return filterScore
+	}
+	if config.SkipCheck && pos.IsCheck() {
+		return filterCheck
+	}
+	if config.SkipCapture && info.Move != common.MoveEmpty && info.Move.CapturedPiece() != common.Empty {
+		return filterCapture
+	}
+	if config.QuietSee && hasWinningCapture(pos) {
+		return filterQuiet
+	}
+	var pieces = common.PopCount(pos.White | pos.Black)
+	if pieces < config.MinPieces ||
+		config.MaxPieces != 0 && pieces > config.MaxPieces {
+		return filterPieces
+	}
+	if config.Dedup {
+		f.mu.Lock()
+		var _, found = f.held[pos.Key]
+		if !found && f.seen != nil {
+			_, found = f.seen[pos.Key]
+			if !found {
+				f.seen[pos.Key] = struct{}{}
+			}
+		}
+		f.mu.Unlock()
+		if found {
+			return filterDuplicate
+		}
+	}
+	if config.MaxPerPieceCount != 0 &&
+		atomic.AddInt64(&f.buckets[pieces], 1) > int64(config.MaxPerPieceCount) {
+		return filterBucket
+	}
+	return filterAccepted
+}
+
+func hasWinningCapture(pos *common.Position) bool {
+	var buffer [common.MaxMoves]common.OrderedMove
+	var child common.Position
+	for _, m := range pos.GenerateCaptures(buffer[:]) {
+		if engine.SeeGE(pos, m.Move, 1) && pos.MakeMove(m.Move, &child) {
+			return true
+		}
+	}
+	return false
+}
+
+// LogStats logs how many positions were accepted and why the rest were dropped.
+func (f *Filter) LogStats() {
+	var total int64
+	for i := range f.stats {
+		total += atomic.LoadInt64(&f.stats[i])
+	}
+	var sb strings.Builder
+	for i, name := range filterReasonNames {
+		var n = atomic.LoadInt64(&f.stats[i])
+		if n != 0 {
+			fmt.Fprintf(&sb, " %v %v (%.1f%%)", name, n, 100*float64(n)/float64(total))
+		}
+	}
+	log.Printf("filter total %v:%v\n", total, sb.String())
+}
+
+// HoldSeen makes positions seen so far, e.g. the validation set, duplicates of every later position.
+// Later positions are checked only against them, so memory does not grow over passes of a streamed dataset,
+// duplicates within a packed dataset are dropped once when it is packed.
+func (f *Filter) HoldSeen() {
+	f.mu.Lock()
+	f.held = f.seen
+	f.seen = nil
+	f.mu.Unlock()
+}
+
+// Reset forgets bucket counts and stats, e.g. before the next pass over a streamed dataset.
+// Seen positions are forgotten too unless they are held.
+func (f *Filter) Reset() {
+	f.mu.Lock()
+	if f.seen != nil {
+		f.seen = make(map[uint64]struct{})
+	}
+	f.mu.Unlock()
+	for i := range f.buckets {
+		atomic.StoreInt64(&f.buckets[i], 0)
+	}
+	for i := range f.stats {
+		atomic.StoreInt64(&f.stats[i], 0)
+	}
+}
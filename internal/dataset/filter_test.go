@@ -0,0 +1,37 @@
+package dataset
+
+import (
+	"testing"
+
+	"github.com/ChizhovVadim/CounterGo/pkg/common"
+)
+
+func TestFilterHoldSeen(t *testing.T) {
+	var positionInfo = func(fen string) PositionInfo {
+		var pos, err = common.NewPositionFromFEN(fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return PositionInfo{Position: pos, Move: common.MoveEmpty}
+	}
+	var validation = positionInfo(common.InitialPositionFen)
+	var training = positionInfo("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1")
+
+	var filter = NewFilter(FilterConfig{Dedup: true})
+	if !filter.Accept(&validation) || filter.Accept(&validation) {
+		t.Fatal("duplicate of validation position is accepted")
+	}
+	filter.HoldSeen()
+	for pass := 0; pass < 2; pass++ {
+		filter.Reset()
+		if filter.Accept(&validation) {
+			t.Fatalf("pass %v: validation position is accepted", pass)
+		}
+		if !filter.Accept(&training) || !filter.Accept(&training) {
+			t.Fatalf("pass %v: training position is dropped", pass)
+		}
+		if filter.stats[filterDuplicate] != 1 || filter.stats[filterAccepted] != 2 {
+			t.Fatalf("pass %v: stats %v", pass, filter.stats)
+		}
+	}
+}
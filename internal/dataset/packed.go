@@ -0,0 +1,140 @@
+package dataset
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/ChizhovVadim/CounterGo/pkg/common"
+)
+
+// PackedPositionSize is the size of a position record in binary dataset files.
+//
+// Layout, little endian:
+//
+//	0  occupancy    uint64
+//	8  pieces       16 bytes, 4 bits per occupied square in ascending square order,
+//	                low nibble first: piece type 1..6, +8 for black pieces
+//	24 side, castle bit 7 set if black to move, bits 0..3 castle rights
+//	25 ep square    0xFF if none
+//	26 rule50       uint8
+//	27 ply          uint8, game ply saturated at 255
+//	28 score        int16, side to move perspective, mate in n is ±(32000-n)
+//	30 result       uint8, 0 black wins, 1 draw, 2 white wins
+//	31 depth        uint8, search depth saturated at 255
+const PackedPositionSize = 32
+
+type PackedPosition [PackedPositionSize]byte
+
+const (
+	packedMateValue  = 32_000
+	packedMateBound  = 31_000
+	packedNoEpSquare = 0xFF
+)
+
+// PackPosition encodes position info and white relative game result (0, 0.5 or 1).
+func PackPosition(info *PositionInfo, gameResult float64) (PackedPosition, error) {
+	var result PackedPosition
+	var p = &info.Position
+	var occupancy = p.White | p.Black
+	if common.PopCount(occupancy) > 32 {
+		return PackedPosition{}, fmt.Errorf("too many pieces %v", p.String())
+	}
+	binary.LittleEndian.PutUint64(result[0:], occupancy)
+	var index = 0
+	for bb := occupancy; bb != 0; bb &= bb - 1 {
+		var sq = common.FirstOne(bb)
+		var nibble = byte(p.WhatPiece(sq))
+		if p.Black&common.SquareMask[sq] != 0 {
+			nibble |= 8
+		}
+		result[8+index/2] |= nibble << (4 * (index % 2))
+		index++
+	}
+	var flags = byte(p.CastleRights & 15)
+	if !p.WhiteMove {
+		flags |= 0x80
+	}
+	result[24] = flags
+	if p.EpSquare == common.SquareNone {
+		result[25] = packedNoEpSquare
+	} else {
+		result[25] = byte(p.EpSquare)
+	}
+	result[26] = byte(common.Min(p.Rule50, 255))
+	result[27] = byte(common.Min(info.Ply, 255))
+	binary.LittleEndian.PutUint16(result[28:], uint16(packScore(info.ScoreMate, info.ScoreCentipawns)))
+	switch gameResult {
+	case 0:
+		result[30] = 0
+	case 0.5:
+		result[30] = 1
+	case 1:
+		result[30] = 2
+	default:
+		return PackedPosition{}, fmt.Errorf("bad game result %v", gameResult)
+	}
+	result[31] = byte(common.Min(info.Depth, 255))
+	return result, nil
+}
+
+// Unpack decodes position info and white relative game result.
+func (pp *PackedPosition) Unpack() (PositionInfo, float64, error) {
+	var occupancy = binary.LittleEndian.Uint64(pp[0:])
+	var pieces [64]int
+	var white uint64
+	var index = 0
+	for bb := occupancy; bb != 0; bb &= bb - 1 {
+		var sq = common.FirstOne(bb)
+		var nibble = (pp[8+index/2] >> (4 * (index % 2))) & 15
+		var piece = int(nibble & 7)
+		if piece < common.Pawn || piece > common.King {
+			return PositionInfo{}, 0, fmt.Errorf("bad packed piece %v", nibble)
+		}
+		pieces[sq] = piece
+		if nibble&8 == 0 {
+			white |= common.SquareMask[sq]
+		}
+		index++
+	}
+	var epSquare = common.SquareNone
+	if pp[25] != packedNoEpSquare {
+		epSquare = int(pp[25])
+	}
+	var pos, err = common.NewPositionFromPieces(&pieces, white, pp[24]&0x80 == 0,
+		int(pp[24]&15), epSquare, int(pp[26]))
+	if err != nil {
+		return PositionInfo{}, 0, err
+	}
+	var scoreMate, scoreCentipawns = unpackScore(int16(binary.LittleEndian.Uint16(pp[28:])))
+	if pp[30] > 2 {
+		return PositionInfo{}, 0, fmt.Errorf("bad packed result %v", pp[30])
+	}
+	return PositionInfo{
+		ScoreMate:       scoreMate,
+		ScoreCentipawns: scoreCentipawns,
+		Position:        pos,
+		Ply:             int(pp[27]),
+		Depth:           int(pp[31]),
+	}, float64(pp[30]) / 2, nil
+}
+
+func packScore(scoreMate, scoreCentipawns int) int16 {
+	if scoreMate > 0 {
+		return int16(packedMateValue - common.Min(scoreMate, packedMateValue-packedMateBound))
+	}
+	if scoreMate < 0 {
+		return int16(-packedMateValue + common.Min(-scoreMate, packedMateValue-packedMateBound))
+	}
+	return int16(common.Max(-packedMateBound+1, common.Min(packedMateBound-1, scoreCentipawns)))
+}
+
+func unpackScore(score int16) (scoreMate, scoreCentipawns int) {
+	var v = int(score)
+	if v >= packedMateBound {
+		return packedMateValue - v, 0
+	}
+	if v <= -packedMateBound {
+		return -packedMateValue - v, 0
+	}
+	return 0, v
+}
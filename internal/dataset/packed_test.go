@@ -0,0 +1,54 @@
+package dataset
+
+import (
+	"testing"
+
+	"github.com/ChizhovVadim/CounterGo/pkg/common"
+)
+
+func TestPackedPosition(t *testing.T) {
+	var tests = []struct {
+		fen             string
+		scoreMate       int
+		scoreCentipawns int
+		gameResult      float64
+	}{
+		{common.InitialPositionFen, 0, 35, 0.5},
+		{"rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3", 0, -120, 1},
+		{"r3k2r/8/8/8/8/8/8/R3K2R b Kq - 7 40", 3, 0, 0},
+		{"8/8/4k3/8/8/8/3QK3/8 b - - 12 60", -5, 0, 1},
+		{"8/8/4k3/8/8/8/4K3/7R w - - 0 1", 0, 40_000, 1},
+	}
+	for _, test := range tests {
+		var pos, err = common.NewPositionFromFEN(test.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var info = PositionInfo{
+			ScoreMate:       test.scoreMate,
+			ScoreCentipawns: test.scoreCentipawns,
+			Position:        pos,
+			Ply:             17,
+			Depth:           9,
+		}
+		pp, err := PackPosition(&info, test.gameResult)
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual, gameResult, err := pp.Unpack()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual.Position.String() != pos.String() || actual.Position.Key != pos.Key {
+			t.Errorf("position %v, expected %v", actual.Position.String(), pos.String())
+		}
+		var expectedCentipawns = test.scoreCentipawns
+		if expectedCentipawns > packedMateBound-1 {
+			expectedCentipawns = packedMateBound - 1
+		}
+		if actual.ScoreMate != test.scoreMate || actual.ScoreCentipawns != expectedCentipawns ||
+			actual.Ply != info.Ply || actual.Depth != info.Depth || gameResult != test.gameResult {
+			t.Errorf("%v: unexpected %+v %v", test.fen, actual, gameResult)
+		}
+	}
+}
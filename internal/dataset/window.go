@@ -0,0 +1,94 @@
+package dataset
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// PackedWindows streams a binary dataset file by windows, so files larger than memory can be trained on.
+// The first ValidationSize positions of the file are the validation set.
+// Every window is split between Workers and decoded in parallel.
+type PackedWindows struct {
+	Path           string
+	ValidationSize int
+	WindowSize     int
+	Workers        int
+}
+
+// DecodeFunc converts positions of a part of window, parts of different workers are decoded concurrently.
+type DecodeFunc func(worker int, positions []PackedPosition) error
+
+// PackedFilePositions returns the number of positions in a binary dataset file.
+func PackedFilePositions(path string) (int, error) {
+	var info, err = os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	return int(info.Size() / PackedPositionSize), nil
+}
+
+// Validation decodes the validation positions as one window.
+func (w *PackedWindows) Validation(decode DecodeFunc) error {
+	var window = make([]PackedPosition, 0, w.ValidationSize)
+	var err = WalkPackedFile(w.Path, func(pp *PackedPosition) error {
+		if len(window) >= w.ValidationSize {
+			return errWindowFull
+		}
+		window = append(window, *pp)
+		return nil
+	})
+	if err != nil && err != errWindowFull {
+		return err
+	}
+	return w.decode(window, decode)
+}
+
+// Walk decodes the training positions window by window, onWindow is called after every window is decoded.
+// The last window may be smaller than WindowSize.
+func (w *PackedWindows) Walk(decode DecodeFunc, onWindow func() error) error {
+	var window = make([]PackedPosition, 0, w.WindowSize)
+	var flush = func() error {
+		var err = w.decode(window, decode)
+		window = window[:0]
+		if err != nil {
+			return err
+		}
+		return onWindow()
+	}
+	var index int
+	var err = WalkPackedFile(w.Path, func(pp *PackedPosition) error {
+		index++
+		if index <= w.ValidationSize {
+			return nil
+		}
+		window = append(window, *pp)
+		if len(window) == cap(window) {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(window) == 0 {
+		return nil
+	}
+	return flush()
+}
+
+func (w *PackedWindows) decode(window []PackedPosition, decode DecodeFunc) error {
+	var g errgroup.Group
+	for i := 0; i < w.Workers; i++ {
+		var i = i
+		var from = len(window) * i / w.Workers
+		var to = len(window) * (i + 1) / w.Workers
+		g.Go(func() error {
+			return decode(i, window[from:to])
+		})
+	}
+	return g.Wait()
+}
+
+var errWindowFull = errors.New("window full")
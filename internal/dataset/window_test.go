@@ -0,0 +1,67 @@
+package dataset
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestPackedWindows(t *testing.T) {
+	const total = 1000
+	var path = filepath.Join(t.TempDir(), "windows.bin")
+	var file, err = os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var writer = NewPackedWriter(file)
+	for i := 0; i < total; i++ {
+		var pp PackedPosition
+		pp[0], pp[1] = byte(i), byte(i>>8)
+		err = writer.Write(&pp)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = writer.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	var windows = PackedWindows{Path: path, ValidationSize: 150, WindowSize: 64, Workers: 3}
+	var mu sync.Mutex
+	var seen = make(map[int]int)
+	var decode = func(worker int, positions []PackedPosition) error {
+		mu.Lock()
+		defer mu.Unlock()
+		for i := range positions {
+			seen[int(positions[i][0])|int(positions[i][1])<<8]++
+		}
+		return nil
+	}
+	err = windows.Validation(decode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != windows.ValidationSize || seen[windows.ValidationSize-1] != 1 {
+		t.Fatalf("validation has %v positions", len(seen))
+	}
+	var windowCount int
+	err = windows.Walk(decode, func() error {
+		windowCount++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < total; i++ {
+		if seen[i] != 1 {
+			t.Fatalf("position %v decoded %v times", i, seen[i])
+		}
+	}
+	var training = total - windows.ValidationSize
+	if expected := (training + windows.WindowSize - 1) / windows.WindowSize; windowCount != expected {
+		t.Errorf("windows %v, expected %v", windowCount, expected)
+	}
+}
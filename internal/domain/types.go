@@ -17,3 +17,10 @@ type FeatureInfo struct {
 	Index int16
 	Value int16
 }
+
+// FeatureGroup is a named range of evaluation weights, e.g. a piece square table.
+type FeatureGroup struct {
+	Name  string
+	Index int
+	Size  int
+}
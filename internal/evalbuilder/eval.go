@@ -2,12 +2,27 @@ package evalbuilder
 
 import (
 	"fmt"
+	"strings"
 
 	counter "github.com/ChizhovVadim/CounterGo/pkg/eval/counter"
 	nnue "github.com/ChizhovVadim/CounterGo/pkg/eval/nnue"
 )
 
+// Get builds evaluation by name. Path to JSON parameter file selects counter evaluation with these weights,
+// path to network file selects nnue evaluation of its architecture.
 func Get(key string) func() interface{} {
+	if strings.HasSuffix(key, ".nn") {
+		return netEvaluation(key)
+	}
+	if strings.HasSuffix(key, ".json") {
+		var weights, err = counter.LoadWeightsFile(key)
+		return func() interface{} {
+			if err != nil {
+				panic(err)
+			}
+			return counter.NewEvaluationServiceWithWeights(weights)
+		}
+	}
 	return func() interface{} {
 		switch key {
 		case "":
@@ -20,7 +35,33 @@ func Get(key string) func() interface{} {
 			return counter.NewEvaluationService()
 		case "nnue":
 			return nnue.NewDefaultEvaluationService()
+		case "nnueq":
+			return nnue.NewDefaultQuantizedEvaluationService()
 		}
 		panic(fmt.Errorf("bad eval %v", key))
 	}
 }
+
+func netEvaluation(path string) func() interface{} {
+	var net, err = nnue.LoadNetFile(path)
+	if err == nil && net.Arch == nnue.ArchPerspective {
+		var weights *nnue.QuantizedPerspectiveWeights
+		weights, err = net.QuantizedPerspectiveWeights()
+		return func() interface{} {
+			if err != nil {
+				panic(err)
+			}
+			return nnue.NewPerspectiveEvaluationService(weights)
+		}
+	}
+	var weights *nnue.QuantizedWeights
+	if err == nil {
+		weights, err = net.QuantizedWeights()
+	}
+	return func() interface{} {
+		if err != nil {
+			panic(err)
+		}
+		return nnue.NewQuantizedEvaluationService(weights)
+	}
+}
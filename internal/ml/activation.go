@@ -1,6 +1,30 @@
 package ml
 
-import "math"
+import (
+	"fmt"
+	"math"
+)
+
+// IVectorActivationFn is activation of float32 layer.
+// Forward sets dst to activation of x, Backward sets dst to errors multiplied by derivative at x.
+type IVectorActivationFn interface {
+	IActivationFn
+	Forward(dst, x []float32)
+	Backward(dst, x, errors []float32)
+}
+
+// NewActivation returns activation of hidden layers by name.
+func NewActivation(name string) (IVectorActivationFn, error) {
+	switch name {
+	case "relu":
+		return &ReLuActivation{}, nil
+	case "crelu":
+		return &ClippedReLuActivation{}, nil
+	case "screlu":
+		return &SCReLuActivation{}, nil
+	}
+	return nil, fmt.Errorf("bad activation name %v", name)
+}
 
 type IActivationFn interface {
 	Sigma(x float64) float64
@@ -28,6 +52,99 @@ func (*ReLuActivation) SigmaPrime(x float64) float64 {
 	return 0
 }
 
+func (*ReLuActivation) Forward(dst, x []float32) {
+	for i, v := range x {
+		if v > 0 {
+			dst[i] = v
+		} else {
+			dst[i] = 0
+		}
+	}
+}
+
+func (*ReLuActivation) Backward(dst, x, errors []float32) {
+	for i, v := range x {
+		if v > 0 {
+			dst[i] = errors[i]
+		} else {
+			dst[i] = 0
+		}
+	}
+}
+
+// ClippedReLuActivation is ReLU clipped to 1.
+type ClippedReLuActivation struct{}
+
+func (*ClippedReLuActivation) Sigma(x float64) float64 {
+	return math.Max(0, math.Min(1, x))
+}
+
+func (*ClippedReLuActivation) SigmaPrime(x float64) float64 {
+	if x > 0 && x < 1 {
+		return 1
+	}
+	return 0
+}
+
+func (*ClippedReLuActivation) Forward(dst, x []float32) {
+	for i, v := range x {
+		if v <= 0 {
+			dst[i] = 0
+		} else if v >= 1 {
+			dst[i] = 1
+		} else {
+			dst[i] = v
+		}
+	}
+}
+
+func (*ClippedReLuActivation) Backward(dst, x, errors []float32) {
+	for i, v := range x {
+		if v > 0 && v < 1 {
+			dst[i] = errors[i]
+		} else {
+			dst[i] = 0
+		}
+	}
+}
+
+// SCReLuActivation is square of clipped ReLU.
+type SCReLuActivation struct{}
+
+func (*SCReLuActivation) Sigma(x float64) float64 {
+	var y = math.Max(0, math.Min(1, x))
+	return y * y
+}
+
+func (*SCReLuActivation) SigmaPrime(x float64) float64 {
+	if x > 0 && x < 1 {
+		return 2 * x
+	}
+	return 0
+}
+
+func (*SCReLuActivation) Forward(dst, x []float32) {
+	for i, v := range x {
+		if v <= 0 {
+			dst[i] = 0
+		} else if v >= 1 {
+			dst[i] = 1
+		} else {
+			dst[i] = v * v
+		}
+	}
+}
+
+func (*SCReLuActivation) Backward(dst, x, errors []float32) {
+	for i, v := range x {
+		if v > 0 && v < 1 {
+			dst[i] = 2 * v * errors[i]
+		} else {
+			dst[i] = 0
+		}
+	}
+}
+
 type SigmoidActivation struct{}
 
 func (s *SigmoidActivation) Sigma(x float64) float64 {
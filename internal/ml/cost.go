@@ -11,6 +11,8 @@ func NewCost(name string) (IModelCost, error) {
 		return &MSECost{}, nil
 	case "abs":
 		return &AbsCost{}, nil
+	case "ce":
+		return &CrossEntropyCost{}, nil
 	}
 	return nil, fmt.Errorf("bad cost name %v", name)
 }
@@ -49,6 +51,22 @@ func (*AbsCost) CostPrime(predicted, target float64) float64 {
 	return 1
 }
 
+// CrossEntropyCost is cost of predicted probability of target, target may be between 0 and 1.
+type CrossEntropyCost struct{}
+
+// predicted probability is clamped, it may be 0 or 1 in float
+const crossEntropyEpsilon = 1e-7
+
+func (*CrossEntropyCost) Cost(predicted, target float64) float64 {
+	predicted = math.Max(crossEntropyEpsilon, math.Min(1-crossEntropyEpsilon, predicted))
+	return -target*math.Log(predicted) - (1-target)*math.Log(1-predicted)
+}
+
+func (*CrossEntropyCost) CostPrime(predicted, target float64) float64 {
+	predicted = math.Max(crossEntropyEpsilon, math.Min(1-crossEntropyEpsilon, predicted))
+	return (predicted - target) / (predicted * (1 - predicted))
+}
+
 type SigmoidMSECost struct{}
 
 func (*SigmoidMSECost) Cost(predicted, target float64) float64 {
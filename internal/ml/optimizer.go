@@ -0,0 +1,70 @@
+package ml
+
+import (
+	"fmt"
+	"math"
+)
+
+// IOptimizer updates state of gradient g and returns weight change.
+type IOptimizer interface {
+	Step(g *Gradient, learningRate float64) float64
+}
+
+type AdamOptimizer struct {
+	Beta1 float64
+	Beta2 float64
+}
+
+func (o *AdamOptimizer) Step(g *Gradient, learningRate float64) float64 {
+	if g.Value == 0 {
+		return 0
+	}
+	g.M1 = g.M1*o.Beta1 + g.Value*(1-o.Beta1)
+	g.M2 = g.M2*o.Beta2 + (g.Value*g.Value)*(1-o.Beta2)
+	return learningRate * g.M1 / (math.Sqrt(g.M2) + 1e-8)
+}
+
+// AdaGradOptimizer keeps sum of squared gradients in M2.
+type AdaGradOptimizer struct{}
+
+func (o *AdaGradOptimizer) Step(g *Gradient, learningRate float64) float64 {
+	if g.Value == 0 {
+		return 0
+	}
+	g.M2 += g.Value * g.Value
+	return learningRate * g.Value / (math.Sqrt(g.M2) + 1e-8)
+}
+
+// MomentumOptimizer is SGD with momentum, velocity is kept in M1.
+type MomentumOptimizer struct {
+	Momentum float64
+}
+
+func (o *MomentumOptimizer) Step(g *Gradient, learningRate float64) float64 {
+	g.M1 = g.M1*o.Momentum + g.Value
+	return learningRate * g.M1
+}
+
+func NewOptimizer(name string, momentum float64) (IOptimizer, error) {
+	switch name {
+	case "adam":
+		return &AdamOptimizer{Beta1: Beta1, Beta2: Beta2}, nil
+	case "adagrad":
+		return &AdaGradOptimizer{}, nil
+	case "sgd":
+		return &MomentumOptimizer{Momentum: momentum}, nil
+	}
+	return nil, fmt.Errorf("bad optimizer %v", name)
+}
+
+// ApplyOptimizer is Apply with optimizer and learning rate of every column.
+func (g *Gradients) ApplyOptimizer(m *Matrix, optimizer IOptimizer, learningRates []float64) {
+	for col := 0; col < g.Cols; col++ {
+		var learningRate = learningRates[col]
+		for row := 0; row < g.Rows; row++ {
+			var i = col*g.Rows + row
+			m.Data[i] -= optimizer.Step(&g.Data[i], learningRate)
+			g.Data[i].Value = 0
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package ml
+
+import (
+	"fmt"
+	"math"
+)
+
+// ILearningRateSchedule returns learning rate of epoch, epochs are numbered from 1.
+type ILearningRateSchedule interface {
+	LearningRate(epoch int) float64
+}
+
+type ConstantSchedule struct {
+	Rate float64
+}
+
+func (s *ConstantSchedule) LearningRate(epoch int) float64 {
+	return s.Rate
+}
+
+// StepSchedule multiplies learning rate by Gamma every Step epochs.
+type StepSchedule struct {
+	Rate  float64
+	Gamma float64
+	Step  int
+}
+
+func (s *StepSchedule) LearningRate(epoch int) float64 {
+	return s.Rate * math.Pow(s.Gamma, float64((epoch-1)/s.Step))
+}
+
+type ExponentialSchedule struct {
+	Rate  float64
+	Gamma float64
+}
+
+func (s *ExponentialSchedule) LearningRate(epoch int) float64 {
+	return s.Rate * math.Pow(s.Gamma, float64(epoch-1))
+}
+
+// CosineSchedule anneals learning rate to zero at the last epoch.
+type CosineSchedule struct {
+	Rate   float64
+	Epochs int
+}
+
+func (s *CosineSchedule) LearningRate(epoch int) float64 {
+	return 0.5 * s.Rate * (1 + math.Cos(math.Pi*float64(epoch-1)/float64(s.Epochs)))
+}
+
+func NewSchedule(name string, rate, gamma float64, step, epochs int) (ILearningRateSchedule, error) {
+	switch name {
+	case "constant":
+		return &ConstantSchedule{Rate: rate}, nil
+	case "step":
+		if step <= 0 {
+			return nil, fmt.Errorf("bad schedule step %v", step)
+		}
+		return &StepSchedule{Rate: rate, Gamma: gamma, Step: step}, nil
+	case "exp":
+		return &ExponentialSchedule{Rate: rate, Gamma: gamma}, nil
+	case "cosine":
+		return &CosineSchedule{Rate: rate, Epochs: epochs}, nil
+	}
+	return nil, fmt.Errorf("bad schedule %v", name)
+}
@@ -0,0 +1,127 @@
+package opengen
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+
+	"github.com/ChizhovVadim/CounterGo/pkg/common"
+)
+
+const maxHeight = 128
+
+// Positions with larger material imbalance are not used as openings.
+const EvalBound = 700
+
+type searchStack struct {
+	rand  *rand.Rand
+	stack [maxHeight]struct {
+		positon common.Position
+		buffer  [common.MaxMoves]common.OrderedMove
+	}
+}
+
+// GenerateRandomPositions sends balanced positions reached by random moves ply deep from start.
+// It never stops by itself, the caller cancels ctx.
+func GenerateRandomPositions(
+	ctx context.Context,
+	r *rand.Rand,
+	start common.Position,
+	ply int,
+	positions chan<- common.Position,
+) error {
+	const height = 0
+	var ss = &searchStack{rand: r}
+	ss.stack[height].positon = start
+	for {
+		var err = search(ctx, ss, ply, height, positions)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func search(ctx context.Context, searchStack *searchStack, depth, height int, positions chan<- common.Position) error {
+	var position = &searchStack.stack[height].positon
+	if depth <= 0 {
+		var eval = EvaluateMaterial(position)
+		if -EvalBound < eval && eval < EvalBound {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case positions <- *position:
+			}
+		}
+		return nil
+	}
+	var ml = position.GenerateMoves(searchStack.stack[height].buffer[:])
+	if len(ml) == 0 {
+		return nil
+	}
+	var child = &searchStack.stack[height+1].positon
+	for i := 0; i < 3; i++ {
+		var move = ml[searchStack.rand.Intn(len(ml))].Move
+		if !position.MakeMove(move, child) {
+			continue
+		}
+		var err = search(ctx, searchStack, depth-1, height+1, positions)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// maxOpeningAttempts limits retries of RandomOpening, start may have no balanced continuation at all.
+const maxOpeningAttempts = 1000
+
+// RandomOpening plays ply random legal moves from start.
+// It retries until the final position has a legal move and is balanced by material.
+func RandomOpening(r *rand.Rand, start common.Position, ply int) (common.Position, error) {
+	var buffer [common.MaxMoves]common.OrderedMove
+	for attempt := 0; attempt < maxOpeningAttempts; attempt++ {
+		var pos = start
+		var ok = true
+		for i := 0; i < ply && ok; i++ {
+			var legal = legalMoves(&pos, buffer[:])
+			if len(legal) == 0 {
+				ok = false
+				break
+			}
+			var child common.Position
+			pos.MakeMove(legal[r.Intn(len(legal))], &child)
+			pos = child
+		}
+		if !ok || len(legalMoves(&pos, buffer[:])) == 0 {
+			continue
+		}
+		var eval = EvaluateMaterial(&pos)
+		if -EvalBound < eval && eval < EvalBound {
+			return pos, nil
+		}
+	}
+	return common.Position{}, fmt.Errorf("no balanced opening after %v random plies from %v", ply, start.String())
+}
+
+func legalMoves(p *common.Position, buffer []common.OrderedMove) []common.Move {
+	var result []common.Move
+	var child common.Position
+	for _, m := range p.GenerateMoves(buffer) {
+		if p.MakeMove(m.Move, &child) {
+			result = append(result, m.Move)
+		}
+	}
+	return result
+}
+
+func EvaluateMaterial(p *common.Position) int {
+	var eval = 100*(common.PopCount(p.Pawns&p.White)-common.PopCount(p.Pawns&p.Black)) +
+		400*(common.PopCount(p.Knights&p.White)-common.PopCount(p.Knights&p.Black)) +
+		400*(common.PopCount(p.Bishops&p.White)-common.PopCount(p.Bishops&p.Black)) +
+		600*(common.PopCount(p.Rooks&p.White)-common.PopCount(p.Rooks&p.Black)) +
+		1200*(common.PopCount(p.Queens&p.White)-common.PopCount(p.Queens&p.Black))
+	if !p.WhiteMove {
+		eval = -eval
+	}
+	return eval
+}
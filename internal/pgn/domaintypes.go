@@ -1,6 +1,10 @@
 package pgn
 
-import "github.com/ChizhovVadim/CounterGo/pkg/common"
+import (
+	"time"
+
+	"github.com/ChizhovVadim/CounterGo/pkg/common"
+)
 
 const (
 	GameResultNone     = "*"
@@ -28,6 +32,7 @@ type Game struct {
 type Comment struct {
 	Depth int
 	Score common.UciScore
+	Time  time.Duration
 }
 
 type Item struct {
@@ -4,9 +4,11 @@ import (
 	"bufio"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 	"unicode"
 
 	"github.com/ChizhovVadim/CounterGo/pkg/common"
@@ -21,12 +23,18 @@ func WalkPgnFile(
 		return err
 	}
 	defer file.Close()
+	return walkPgn(file, onGame)
+}
 
+func walkPgn(
+	r io.Reader,
+	onGame func(GameRaw) error,
+) error {
 	var tags []string
 	var body = &strings.Builder{}
 	var hasBody bool
 
-	var scanner = bufio.NewScanner(file)
+	var scanner = bufio.NewScanner(r)
 	for scanner.Scan() {
 		var line = scanner.Text()
 		if strings.HasPrefix(line, "[") {
@@ -190,10 +198,13 @@ func parseComment(comment string) (Comment, error) {
 	var fields = strings.Fields(comment)
 	if len(fields) >= 2 {
 		var s string
+		var timeIndex int
 		if strings.HasPrefix(fields[0], "(") {
 			s = fields[1]
+			timeIndex = 2
 		} else {
 			s = fields[0]
+			timeIndex = 1
 		}
 		if s != "" {
 			var index = strings.Index(s, "/")
@@ -221,9 +232,14 @@ func parseComment(comment string) (Comment, error) {
 				if err != nil {
 					return Comment{}, err
 				}
+				var elapsed time.Duration
+				if timeIndex < len(fields) {
+					elapsed = parseSeconds(fields[timeIndex])
+				}
 				return Comment{
 					Score: uciScore,
 					Depth: depth,
+					Time:  elapsed,
 				}, nil
 			}
 		}
@@ -232,3 +248,12 @@ func parseComment(comment string) (Comment, error) {
 }
 
 var errParseComment = errors.New("parse comment failed")
+
+func parseSeconds(s string) time.Duration {
+	s = strings.TrimSuffix(strings.TrimRight(s, ","), "s")
+	var seconds, err = strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -1,7 +1,9 @@
 package pgn
 
 import (
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestPgn(t *testing.T) {
@@ -89,3 +91,41 @@ Re1 {(Kc6) -0.91/28 27s} 88. Rh6 {(Nd4) +1.69/29 15s} Kc6 {(Kc6) -0.91/29 30s}
 {(Kc6) -1.10/29 20s} 98. Nxd6 {(Nxd6) +2.02/27 18s} 1/2-1/2
 
 `
+
+func TestWriteGame(t *testing.T) {
+	var game, err = ParseGame(testGame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sb = &strings.Builder{}
+	err = WriteGame(sb, []Tag{{"Event", "test"}, {"Result", game.Result}}, game, "Draw by adjudication")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var games []GameRaw
+	err = walkPgn(strings.NewReader(sb.String()), func(gr GameRaw) error {
+		games = append(games, gr)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(games) != 1 {
+		t.Fatalf("expected 1 game, got %v", len(games))
+	}
+	game2, err := ParseGame(games[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if game2.Result != game.Result || len(game2.Items) != len(game.Items) {
+		t.Fatalf("game mismatch %v %v", game2.Result, len(game2.Items))
+	}
+	for i := range game.Items {
+		var expected, actual = game.Items[i], game2.Items[i]
+		expected.Time = expected.Time.Round(time.Millisecond)
+		actual.Time = actual.Time.Round(time.Millisecond)
+		if expected != actual {
+			t.Fatalf("item %v mismatch %+v %+v", i, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package pgn
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ChizhovVadim/CounterGo/pkg/common"
+)
+
+const maxLineLength = 80
+
+// WriteGame writes game in PGN. Tags are written in the given order.
+// Move comments use the format that parseComment reads back: {+0.35/12 0.123s}.
+// The optional termination text is appended to the comment of the last move.
+func WriteGame(w io.Writer, tags []Tag, game Game, termination string) error {
+	var bw = bufio.NewWriter(w)
+	for _, tag := range tags {
+		fmt.Fprintf(bw, "[%v \"%v\"]\n", tag.Key, escapeTagValue(tag.Value))
+	}
+	bw.WriteString("\n")
+
+	var startFen = game.Fen
+	if startFen == "" {
+		startFen = common.InitialPositionFen
+	}
+	var pos, err = common.NewPositionFromFEN(startFen)
+	if err != nil {
+		return err
+	}
+	var moveNumber = 1
+	if index := strings.LastIndex(startFen, " "); index >= 0 {
+		fmt.Sscan(startFen[index+1:], &moveNumber)
+	}
+
+	var lw = &lineWriter{w: bw}
+	for i := range game.Items {
+		var item = &game.Items[i]
+		if pos.WhiteMove {
+			lw.WriteToken(fmt.Sprintf("%v.", moveNumber))
+		} else if i == 0 {
+			lw.WriteToken(fmt.Sprintf("%v...", moveNumber))
+		}
+		var san = common.FormatMoveSAN(&pos, item.Move)
+		if san == "" {
+			return fmt.Errorf("illegal move %v in position %v", item.Move, pos.String())
+		}
+		lw.WriteToken(san)
+		var comment = formatComment(item.Comment)
+		if i == len(game.Items)-1 && termination != "" {
+			if comment == "" {
+				comment = termination
+			} else {
+				comment += ", " + termination
+			}
+		}
+		if comment != "" {
+			lw.WriteToken("{" + comment + "}")
+		}
+		if !pos.WhiteMove {
+			moveNumber++
+		}
+		var child common.Position
+		pos.MakeMove(item.Move, &child)
+		pos = child
+	}
+	var result = game.Result
+	if result == "" {
+		result = GameResultNone
+	}
+	lw.WriteToken(result)
+	bw.WriteString("\n\n")
+	return bw.Flush()
+}
+
+func formatComment(c Comment) string {
+	if c.Depth == 0 {
+		return ""
+	}
+	var score string
+	if c.Score.Mate > 0 {
+		score = fmt.Sprintf("+M%v", c.Score.Mate)
+	} else if c.Score.Mate < 0 {
+		score = fmt.Sprintf("-M%v", -c.Score.Mate)
+	} else {
+		score = fmt.Sprintf("%+.2f", float64(c.Score.Centipawns)/100)
+	}
+	return fmt.Sprintf("%v/%v %.3fs", score, c.Depth, c.Time.Seconds())
+}
+
+func escapeTagValue(s string) string {
+	s = strings.ReplaceAll(s, "\\", "\\\\")
+	return strings.ReplaceAll(s, "\"", "\\\"")
+}
+
+type lineWriter struct {
+	w       *bufio.Writer
+	lineLen int
+}
+
+func (lw *lineWriter) WriteToken(token string) {
+	if lw.lineLen != 0 {
+		if lw.lineLen+1+len(token) > maxLineLength {
+			lw.w.WriteString("\n")
+			lw.lineLen = 0
+		} else {
+			lw.w.WriteString(" ")
+			lw.lineLen++
+		}
+	}
+	lw.w.WriteString(token)
+	lw.lineLen += len(token)
+}
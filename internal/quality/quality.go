@@ -16,44 +16,66 @@ type IEvaluator interface {
 }
 
 func RunQuality(evaluator IEvaluator, validationPath string) error {
-	file, err := os.Open(validationPath)
+	mseCost, err := Cost(evaluator, validationPath)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+	log.Printf("mse cost: %f", mseCost)
+	return nil
+}
 
-	var sum, sumSq float64
-	var count int
+// Cost returns mse cost of evaluator on validation dataset.
+func Cost(evaluator IEvaluator, validationPath string) (float64, error) {
+	entries, err := loadEntries(validationPath)
+	if err != nil {
+		return 0, err
+	}
+	return computeCost(entries, func(i int) float64 {
+		return evaluator.EvaluateProb(&entries[i].pos)
+	}), nil
+}
+
+func loadEntries(validationPath string) ([]Entry, error) {
+	file, err := os.Open(validationPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
 
+	var result []Entry
 	var scanner = bufio.NewScanner(file)
 	for scanner.Scan() {
 		var s = scanner.Text()
 		var entry, err = parseEntry(s)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
-		pos, err := common.NewPositionFromFEN(entry.fen)
+		entry.pos, err = common.NewPositionFromFEN(entry.fen)
 		if err != nil {
-			return err
+			return nil, err
 		}
+		result = append(result, entry)
+	}
+	return result, scanner.Err()
+}
 
-		var eval = evaluator.EvaluateProb(&pos)
-		var x = eval - entry.target
+// computeCost returns mse cost of predicted probabilities.
+func computeCost(entries []Entry, predict func(i int) float64) float64 {
+	var sum, sumSq float64
+	for i := range entries {
+		var x = predict(i) - entries[i].target
 		sum += math.Abs(x)
 		sumSq += x * x
-		count += 1
 	}
-
-	//var absCost = sum / float64(count)
-	var mseCost = sumSq / float64(count)
+	//var absCost = sum / float64(len(entries))
 	//log.Printf("abs cost: %f", absCost)
-	log.Printf("mse cost: %f", mseCost)
-	return nil
+	return sumSq / float64(len(entries))
 }
 
 type Entry struct {
 	fen    string
+	pos    common.Position
 	target float64
 }
 
@@ -0,0 +1,62 @@
+package quality
+
+import (
+	"log"
+	"math"
+
+	"github.com/ChizhovVadim/CounterGo/internal/ml"
+	"github.com/ChizhovVadim/CounterGo/pkg/common"
+)
+
+// ICentipawnEvaluator returns evaluation in centipawns from side to move point of view.
+type ICentipawnEvaluator interface {
+	Evaluate(pos *common.Position) int
+}
+
+// FitSigmoidScale finds K that minimizes mse cost of Sigmoid(K*eval) on validation dataset.
+// Cost is unimodal by K, so golden section search is used.
+func FitSigmoidScale(evaluator ICentipawnEvaluator, validationPath string) (float64, error) {
+	entries, err := loadEntries(validationPath)
+	if err != nil {
+		return 0, err
+	}
+	var evals = make([]float64, len(entries))
+	for i := range entries {
+		var pos = &entries[i].pos
+		var eval = evaluator.Evaluate(pos)
+		if !pos.WhiteMove {
+			eval = -eval
+		}
+		evals[i] = float64(eval)
+	}
+	var cost = func(k float64) float64 {
+		return computeCost(entries, func(i int) float64 {
+			return ml.Sigmoid(k * evals[i])
+		})
+	}
+
+	const (
+		minScale  = 0.0
+		maxScale  = 0.1
+		tolerance = 1e-6
+	)
+	var invPhi = (math.Sqrt(5) - 1) / 2
+	var a, b = minScale, maxScale
+	var c = b - invPhi*(b-a)
+	var d = a + invPhi*(b-a)
+	var fc, fd = cost(c), cost(d)
+	for b-a > tolerance {
+		if fc < fd {
+			b, d, fd = d, c, fc
+			c = b - invPhi*(b-a)
+			fc = cost(c)
+		} else {
+			a, c, fc = c, d, fd
+			d = a + invPhi*(b-a)
+			fd = cost(d)
+		}
+	}
+	var k = (a + b) / 2
+	log.Printf("sigmoid scale: %f, centipawn scale: %.1f, mse cost: %f\n", k, 1/k, cost(k))
+	return k, nil
+}
@@ -0,0 +1,233 @@
+package tablebase
+
+import (
+	"sync"
+
+	"github.com/ChizhovVadim/CounterGo/pkg/common"
+)
+
+// MaxPieces is the largest number of pieces of probed positions, kings included.
+const MaxPieces = 3
+
+const (
+	unknown int8 = iota
+	win          // side to move wins
+	loss
+	draw // draws and illegal positions
+)
+
+// positions are indexed by side to move, white king, black king and the extra piece
+const tableSize = 2 * 64 * 64 * 64
+
+// Tablebase has WDL of every position with kings and at most one more piece.
+// Tables are generated by retrograde iteration on the first probe.
+type Tablebase struct {
+	once   sync.Once
+	tables [common.King][]int8 // by type of the extra piece, the piece is white in tables
+}
+
+func New() *Tablebase {
+	return &Tablebase{}
+}
+
+// ProbeWDL returns 1 if the side to move wins, 0 for a draw and -1 for a loss.
+// Castling rights and the fifty move rule are ignored.
+func (tb *Tablebase) ProbeWDL(p *common.Position) (wdl int, ok bool) {
+	var pieces = p.White | p.Black
+	var count = common.PopCount(pieces)
+	if count > MaxPieces {
+		return 0, false
+	}
+	if count == 2 {
+		return 0, true
+	}
+	var sq = common.FirstOne(pieces &^ p.Kings)
+	var pt, white = p.GetPieceTypeAndSide(sq)
+	if pt == common.Knight || pt == common.Bishop {
+		return 0, true
+	}
+	var wk, bk, whiteMove = p.KingSq(true), p.KingSq(false), p.WhiteMove
+	if !white {
+		// colours are swapped on the flipped board
+		wk, bk, sq = common.FlipSquare(bk), common.FlipSquare(wk), common.FlipSquare(sq)
+		whiteMove = !whiteMove
+	}
+	tb.once.Do(tb.generate)
+	switch tb.tables[pt][index(whiteMove, wk, bk, sq)] {
+	case win:
+		return 1, true
+	case loss:
+		return -1, true
+	}
+	return 0, true
+}
+
+func (tb *Tablebase) generate() {
+	// pawn promotes to pieces of the other tables
+	for _, pt := range []int{common.Queen, common.Rook, common.Pawn} {
+		tb.tables[pt] = tb.solve(pt)
+	}
+}
+
+func index(whiteMove bool, wk, bk, sq int) int {
+	var side = 0
+	if !whiteMove {
+		side = 1
+	}
+	return ((side*64+wk)*64+bk)*64 + sq
+}
+
+// solve finds positions won by moving to a lost position and lost by moving only to won ones,
+// the rest are draws. White never loses and black never wins.
+func (tb *Tablebase) solve(pt int) []int8 {
+	var result = make([]int8, tableSize)
+	var offsets = make([]int32, tableSize+1)
+	var children []int32
+	for i := range result {
+		offsets[i] = int32(len(children))
+		var sq, bk, wk, whiteMove = i % 64, i / 64 % 64, i / (64 * 64) % 64, i < tableSize/2
+		if whiteMove {
+			children, result[i] = tb.expandWhite(pt, wk, bk, sq, children)
+		} else {
+			children, result[i] = expandBlack(pt, wk, bk, sq, children)
+		}
+	}
+	offsets[tableSize] = int32(len(children))
+
+	for changed := true; changed; {
+		changed = false
+		for i := range result {
+			if result[i] != unknown {
+				continue
+			}
+			var moves = children[offsets[i]:offsets[i+1]]
+			if i < tableSize/2 {
+				for _, child := range moves {
+					if result[child] == loss {
+						result[i] = win
+						changed = true
+						break
+					}
+				}
+			} else {
+				var lost = true
+				for _, child := range moves {
+					if result[child] != win {
+						lost = false
+						break
+					}
+				}
+				if lost {
+					result[i] = loss
+					changed = true
+				}
+			}
+		}
+	}
+	for i := range result {
+		if result[i] == unknown {
+			result[i] = draw
+		}
+	}
+	return result
+}
+
+func legal(pt, wk, bk, sq int, whiteMove bool) bool {
+	if wk == bk || wk == sq || bk == sq ||
+		common.KingAttacks[wk]&common.SquareMask[bk] != 0 {
+		return false
+	}
+	if pt == common.Pawn && (common.Rank(sq) == 0 || common.Rank(sq) == 7) {
+		return false
+	}
+	// side not to move is not in check
+	return !whiteMove || attacks(pt, sq, squares(wk, bk, sq))&common.SquareMask[bk] == 0
+}
+
+func attacks(pt, sq int, occ uint64) uint64 {
+	switch pt {
+	case common.Queen:
+		return common.QueenAttacks(sq, occ)
+	case common.Rook:
+		return common.RookAttacks(sq, occ)
+	case common.Pawn:
+		return common.PawnAttacks(sq, true)
+	}
+	return 0
+}
+
+func squares(sqs ...int) uint64 {
+	var result uint64
+	for _, sq := range sqs {
+		result |= common.SquareMask[sq]
+	}
+	return result
+}
+
+// expandWhite appends positions after white moves. Promotions are resolved by the other tables.
+func (tb *Tablebase) expandWhite(pt, wk, bk, sq int, children []int32) ([]int32, int8) {
+	if !legal(pt, wk, bk, sq, true) {
+		return children, draw
+	}
+	var moves int
+	for x := common.KingAttacks[wk] &^ common.KingAttacks[bk] &^ common.SquareMask[sq]; x != 0; x &= x - 1 {
+		moves++
+		children = append(children, int32(index(false, common.FirstOne(x), bk, sq)))
+	}
+	var targets uint64
+	if pt == common.Pawn {
+		var occ = squares(wk, bk, sq)
+		var push = sq + 8
+		if occ&common.SquareMask[push] == 0 {
+			if common.Rank(push) == 7 {
+				moves++
+				for _, promotion := range []int{common.Queen, common.Rook} {
+					if tb.tables[promotion][index(false, wk, bk, push)] == loss {
+						return children, win
+					}
+				}
+			} else {
+				targets |= common.SquareMask[push]
+				if common.Rank(sq) == 1 && occ&common.SquareMask[push+8] == 0 {
+					targets |= common.SquareMask[push+8]
+				}
+			}
+		}
+	} else {
+		targets = attacks(pt, sq, squares(wk, bk, sq)) &^ squares(wk, bk)
+	}
+	for x := targets; x != 0; x &= x - 1 {
+		moves++
+		children = append(children, int32(index(false, wk, bk, common.FirstOne(x))))
+	}
+	if moves == 0 {
+		return children, draw
+	}
+	return children, unknown
+}
+
+// expandBlack appends positions after black king moves. Capture of the piece is a draw.
+func expandBlack(pt, wk, bk, sq int, children []int32) ([]int32, int8) {
+	if !legal(pt, wk, bk, sq, false) {
+		return children, draw
+	}
+	var moves int
+	for x := common.KingAttacks[bk] &^ common.KingAttacks[wk]; x != 0; x &= x - 1 {
+		var to = common.FirstOne(x)
+		if to == sq {
+			return children, draw
+		}
+		if attacks(pt, sq, squares(wk, to, sq))&common.SquareMask[to] != 0 {
+			continue
+		}
+		moves++
+		children = append(children, int32(index(true, wk, to, sq)))
+	}
+	if moves == 0 {
+		if attacks(pt, sq, squares(wk, bk, sq))&common.SquareMask[bk] != 0 {
+			return children, loss
+		}
+		return children, draw
+	}
+	return children, unknown
+}
@@ -0,0 +1,45 @@
+package tablebase
+
+import (
+	"testing"
+
+	"github.com/ChizhovVadim/CounterGo/pkg/common"
+)
+
+func TestProbeWDL(t *testing.T) {
+	var tests = []struct {
+		fen string
+		wdl int
+	}{
+		{"8/8/8/4k3/8/8/8/K7 w - - 0 1", 0},
+		{"8/8/8/4k3/8/8/8/KQ6 w - - 0 1", 1},
+		{"8/8/8/4k3/8/8/8/KQ6 b - - 0 1", -1},
+		{"8/8/8/4k3/8/8/8/KR6 b - - 0 1", -1},
+		{"8/8/8/4k3/8/8/8/KB6 w - - 0 1", 0},
+		{"k7/2Q5/1K6/8/8/8/8/8 b - - 0 1", 0},  // stalemate
+		{"k7/1Q6/1K6/8/8/8/8/8 b - - 0 1", -1}, // mate
+		{"k7/1Q6/8/8/8/8/8/7K b - - 0 1", 0},   // undefended queen is captured
+		{"k7/8/8/8/8/8/P7/K7 w - - 0 1", 0},    // rook pawn with king in front
+		{"7k/8/8/8/8/8/P7/K7 w - - 0 1", 1},    // pawn outruns the king
+		{"4k3/8/8/8/8/8/4P3/4K3 b - - 0 1", 0}, // opposition
+		{"4k3/8/8/8/8/4K3/4P3/8 b - - 0 1", -1},
+		{"8/8/8/8/8/8/1q6/K6k w - - 0 1", 0},  // undefended queen is captured
+		{"k7/8/8/8/8/6q1/8/K7 b - - 0 1", 1},  // colours are swapped
+		{"k7/8/8/8/8/6q1/8/K7 w - - 0 1", -1}, // colours are swapped
+	}
+	var tb = New()
+	for _, test := range tests {
+		var pos, err = common.NewPositionFromFEN(test.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wdl, ok := tb.ProbeWDL(&pos)
+		if !ok || wdl != test.wdl {
+			t.Errorf("%v: wdl %v ok %v, expected %v", test.fen, wdl, ok, test.wdl)
+		}
+	}
+	var pos, _ = common.NewPositionFromFEN(common.InitialPositionFen)
+	if _, ok := tb.ProbeWDL(&pos); ok {
+		t.Error("initial position is probed")
+	}
+}
@@ -17,7 +17,7 @@ func SolveTactic(tests []EpdItem, eng IEngine, moveTime time.Duration) error {
 	var start = time.Now()
 	var total, solved int
 	for _, test := range tests {
-		var searchResult = executeTest(test, eng, moveTime)
+		var searchResult = executeTest(test, eng, common.LimitsType{MoveTime: int(moveTime.Milliseconds())})
 		var passed = isTestPassed(test, searchResult.MainLine[0])
 
 		total++
@@ -34,6 +34,18 @@ func SolveTactic(tests []EpdItem, eng IEngine, moveTime time.Duration) error {
 	return nil
 }
 
+// CountSolved searches every test with limits and returns number of solved tests.
+func CountSolved(tests []EpdItem, eng IEngine, limits common.LimitsType) int {
+	var solved int
+	for _, test := range tests {
+		var searchResult = executeTest(test, eng, limits)
+		if isTestPassed(test, searchResult.MainLine[0]) {
+			solved++
+		}
+	}
+	return solved
+}
+
 func cancelSearch(test EpdItem, cancel context.CancelFunc) func(si common.SearchInfo) {
 	var count = 0
 	return func(si common.SearchInfo) {
@@ -57,12 +69,12 @@ func isTestPassed(test EpdItem, foundMove common.Move) bool {
 	return false
 }
 
-func executeTest(test EpdItem, uciEngine IEngine, moveTime time.Duration) common.SearchInfo {
+func executeTest(test EpdItem, uciEngine IEngine, limits common.LimitsType) common.SearchInfo {
 	var ctx, cancel = context.WithCancel(context.Background())
 	defer cancel()
 	var searchParams = common.SearchParams{
 		Positions: []common.Position{test.position},
-		Limits:    common.LimitsType{MoveTime: int(moveTime.Milliseconds())},
+		Limits:    limits,
 		Progress:  cancelSearch(test, cancel),
 	}
 	return uciEngine.Search(ctx, searchParams)
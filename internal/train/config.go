@@ -0,0 +1,36 @@
+package train
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+)
+
+type Config struct {
+	Epochs        int
+	Lambda        LambdaSchedule
+	Concurrency   int
+	NetFolderPath string      // network of every epoch is saved here
+	ResumePath    string      // network file to continue training from, Adam moments start from zero
+	StartEpoch    int         // epochs done before resume, used by lambda schedule and network names
+	Experiment    interface{} // saved as JSON next to every network if not nil
+	// Evaluate is optional, its result is added to report of every network.
+	Evaluate func(netPath string) (interface{}, error)
+}
+
+// Report of network is written next to network file after every epoch.
+type Report struct {
+	Epoch          int         `json:"epoch"`
+	Lambda         float64     `json:"lambda"`
+	ValidationCost float64     `json:"validationCost"`
+	Evaluation     interface{} `json:"evaluation,omitempty"`
+}
+
+// saveNetJson writes v next to network file, suffix replaces .nn extension.
+func saveNetJson(netPath, suffix string, v interface{}) error {
+	var data, err = json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(strings.TrimSuffix(netPath, ".nn")+suffix, data, 0644)
+}
@@ -5,7 +5,6 @@ import (
 	"sync"
 
 	"github.com/ChizhovVadim/CounterGo/internal/dataset"
-	"github.com/ChizhovVadim/CounterGo/internal/math"
 	"github.com/ChizhovVadim/CounterGo/internal/pgn"
 	"github.com/ChizhovVadim/CounterGo/pkg/common"
 	"golang.org/x/sync/errgroup"
@@ -14,12 +13,16 @@ import (
 func LoadDataset(
 	featureProvider func() IFeatureProvider,
 	gamesFolder string,
+	filter *dataset.Filter,
 	sigmoidScale float64,
-	searchRatio float64,
 	maxSize int,
 	concurrency int,
 	mirrorPos bool,
 ) ([]Sample, error) {
+	defer filter.LogStats()
+	if dataset.IsPackedDataset(gamesFolder) {
+		return loadPackedDataset(featureProvider, gamesFolder, filter, sigmoidScale, maxSize, concurrency, mirrorPos)
+	}
 	var datasetReady = make(chan struct{})
 	var games = make(chan pgn.GameRaw, 16)
 	var results = make(chan []Sample, 16)
@@ -43,7 +46,7 @@ func LoadDataset(
 		wg.Add(1)
 		g.Go(func() error {
 			defer wg.Done()
-			return analyzeGames(ctx, games, results, featureProvider(), sigmoidScale, searchRatio, mirrorPos)
+			return analyzeGames(ctx, games, results, featureProvider(), filter, sigmoidScale, mirrorPos)
 		})
 	}
 
@@ -79,8 +82,8 @@ func analyzeGames(
 	games <-chan pgn.GameRaw,
 	samples chan<- []Sample,
 	featureProvider IFeatureProvider,
+	filter *dataset.Filter,
 	sigmoidScale float64,
-	searchRatio float64,
 	mirrorPos bool,
 ) error {
 	for gameRaw := range games {
@@ -90,22 +93,10 @@ func analyzeGames(
 		}
 		var chunk []Sample
 		for i := range game.Positions {
-			var pos = &game.Positions[i]
-			var features = featureProvider.ComputeFeatures(&pos.Position)
-			var target = computeTarget(pos.Position.WhiteMove, pos.ScoreMate, pos.ScoreCentipawns, sigmoidScale, searchRatio, game.GameResult)
-			chunk = append(chunk, Sample{
-				target: float32(target),
-				input:  features,
-			})
-			if mirrorPos {
-				var mirror = common.MirrorPosition(&pos.Position)
-				var mirrorFeatures = featureProvider.ComputeFeatures(&mirror)
-				var mirrorTarget = 1 - target
-				chunk = append(chunk, Sample{
-					target: float32(mirrorTarget),
-					input:  mirrorFeatures,
-				})
+			if !filter.Accept(&game.Positions[i]) {
+				continue
 			}
+			chunk = appendSamples(chunk, featureProvider, &game.Positions[i], game.GameResult, sigmoidScale, mirrorPos)
 		}
 		if len(chunk) != 0 {
 			samples <- chunk
@@ -114,26 +105,29 @@ func analyzeGames(
 	return nil
 }
 
-func computeTarget(
-	wstm bool,
-	scoreMate int,
-	scoreCentipawns int,
-	sigmoidScale float64,
-	searchRatio float64,
+func appendSamples(
+	samples []Sample,
+	featureProvider IFeatureProvider,
+	pos *dataset.PositionInfo,
 	gameResult float64,
-) float64 {
-	var targetBySearch float64
-	if scoreMate != 0 {
-		if scoreMate > 0 {
-			targetBySearch = 1
-		} else {
-			targetBySearch = 0
-		}
-	} else {
-		targetBySearch = math.Sigmoid(sigmoidScale * float64(scoreCentipawns))
-	}
-	if !wstm {
-		targetBySearch = 1 - targetBySearch
+	sigmoidScale float64,
+	mirrorPos bool,
+) []Sample {
+	var features = featureProvider.ComputeFeatures(&pos.Position)
+	var score = searchTarget(pos.Position.WhiteMove, pos.ScoreMate, pos.ScoreCentipawns, sigmoidScale)
+	samples = append(samples, Sample{
+		input:  features,
+		score:  float32(score),
+		result: float32(gameResult),
+	})
+	if mirrorPos {
+		var mirror = common.MirrorPosition(&pos.Position)
+		var mirrorFeatures = featureProvider.ComputeFeatures(&mirror)
+		samples = append(samples, Sample{
+			input:  mirrorFeatures,
+			score:  float32(1 - score),
+			result: float32(1 - gameResult),
+		})
 	}
-	return targetBySearch*searchRatio + gameResult*(1-searchRatio)
+	return samples
 }
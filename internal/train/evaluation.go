@@ -2,6 +2,7 @@ package train
 
 import (
 	"github.com/ChizhovVadim/CounterGo/pkg/common"
+	nnue "github.com/ChizhovVadim/CounterGo/pkg/eval/nnue"
 )
 
 type EvalService struct {
@@ -14,14 +15,18 @@ func (e *EvalService) EvaluateProb(pos *common.Position) float64 {
 	return e.model.Forward(&features)
 }
 
-func NewEvalService(fp IFeatureProvider, filepath string) *EvalService {
-	var model = NewModel(fp.FeatureSize(), 512)
-	var err = model.LoadWeights(filepath)
+// NewEvalService evaluates by network file in float, topology and features are read from the file.
+func NewEvalService(filepath string) (*EvalService, error) {
+	var model, err = LoadModel(filepath)
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+	var fp IFeatureProvider = &Feature768Provider{}
+	if model.arch == nnue.ArchPerspective {
+		fp = &FeaturePerspectiveProvider{Layout: model.layout}
 	}
 	return &EvalService{
 		featureProvider: fp,
 		model:           model,
-	}
+	}, nil
 }
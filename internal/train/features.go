@@ -3,6 +3,7 @@ package train
 import (
 	"github.com/ChizhovVadim/CounterGo/internal/domain"
 	"github.com/ChizhovVadim/CounterGo/pkg/common"
+	nnue "github.com/ChizhovVadim/CounterGo/pkg/eval/nnue"
 )
 
 type Feature768Provider struct{}
@@ -26,6 +27,37 @@ func (p *Feature768Provider) ComputeFeatures(pos *common.Position) Input {
 		})
 	}
 	return Input{
-		Features: input,
+		Features:   input,
+		PieceCount: int8(len(input)),
 	}
 }
+
+// FeaturePerspectiveProvider computes inputs of both sides for perspective networks.
+type FeaturePerspectiveProvider struct {
+	Layout nnue.PerspectiveLayout
+}
+
+func (p *FeaturePerspectiveProvider) FeatureSize() int { return p.Layout.InputSize() }
+
+func (p *FeaturePerspectiveProvider) ComputeFeatures(pos *common.Position) Input {
+	return Input{
+		Features:   p.sideFeatures(pos, pos.WhiteMove),
+		Opponent:   p.sideFeatures(pos, !pos.WhiteMove),
+		WhiteMove:  pos.WhiteMove,
+		PieceCount: int8(common.PopCount(pos.AllPieces())),
+	}
+}
+
+func (p *FeaturePerspectiveProvider) sideFeatures(pos *common.Position, side bool) []domain.FeatureInfo {
+	var kingSq = pos.KingSq(side)
+	var input = make([]domain.FeatureInfo, 0, common.PopCount(pos.AllPieces()))
+	for x := pos.AllPieces(); x != 0; x &= x - 1 {
+		var sq = common.FirstOne(x)
+		var pt, pieceSide = pos.GetPieceTypeAndSide(sq)
+		input = append(input, domain.FeatureInfo{
+			Index: int16(p.Layout.FeatureIndex(side, kingSq, pt, pieceSide, sq)),
+			Value: 1,
+		})
+	}
+	return input
+}
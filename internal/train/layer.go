@@ -1,130 +1,196 @@
 package train
 
 import (
+	"math"
 	"math/rand"
 
 	"github.com/ChizhovVadim/CounterGo/internal/domain"
 	"github.com/ChizhovVadim/CounterGo/internal/ml"
+	nnue "github.com/ChizhovVadim/CounterGo/pkg/eval/nnue"
 )
 
-type Neuron struct {
-	Activation float64
-	Error      float64
-	Prime      float64
+// param is float32 weights with gradients and Adam moments.
+type param struct {
+	values []float32
+	grads  []float32
+	m1     []float32
+	m2     []float32
 }
 
-type Layer struct {
-	activationFn ml.IActivationFn
-	outputs      []Neuron
-	weights      ml.Matrix
-	biases       ml.Matrix
-	wGradients   ml.Gradients
-	bGradients   ml.Gradients
+func newParam(size int) *param {
+	return &param{
+		values: make([]float32, size),
+		grads:  make([]float32, size),
+		m1:     make([]float32, size),
+		m2:     make([]float32, size),
+	}
 }
 
-func (l *Layer) ThreadCopy() *Layer {
-	return &Layer{
-		activationFn: l.activationFn,
-		outputs:      make([]Neuron, len(l.outputs)),
-		weights:      l.weights,
-		biases:       l.biases,
-		wGradients:   ml.NewGradients(l.wGradients.Rows, l.wGradients.Cols),
-		bGradients:   ml.NewGradients(l.bGradients.Rows, l.bGradients.Cols),
+// apply updates weights from lo to hi by Adam and resets gradients.
+// Weights without gradient keep their moments, most inputs of hidden layer are not in batch.
+func (p *param) apply(lo, hi int) {
+	for i := lo; i < hi; i++ {
+		var g = p.grads[i]
+		if g == 0 {
+			continue
+		}
+		p.grads[i] = 0
+		p.m1[i] = p.m1[i]*ml.Beta1 + g*(1-ml.Beta1)
+		p.m2[i] = p.m2[i]*ml.Beta2 + g*g*(1-ml.Beta2)
+		p.values[i] -= ml.LearningRate * p.m1[i] / (float32(math.Sqrt(float64(p.m2[i]))) + 1e-8)
 	}
 }
 
-func NewLayer(
-	inputSize int,
-	outputs []Neuron,
-	activationFn ml.IActivationFn,
-) *Layer {
-	var outputSize = len(outputs)
-	return &Layer{
-		outputs:      outputs,
-		activationFn: activationFn,
-		weights:      ml.NewMatrix(outputSize, inputSize),
-		biases:       ml.NewMatrix(outputSize, 1),
-		wGradients:   ml.NewGradients(outputSize, inputSize),
-		bGradients:   ml.NewGradients(outputSize, 1),
+func initUniform(rnd *rand.Rand, data []float32, variance float64) {
+	var values = make([]float64, len(data))
+	ml.InitUniform(rnd, values, variance)
+	for i, x := range values {
+		data[i] = float32(x)
 	}
 }
 
-func (layer *Layer) InitWeightsSigmoid(rnd *rand.Rand) *Layer {
-	var outputSize = layer.weights.Rows
-	var inputSize = layer.weights.Cols
-	var variance = 2.0 / float64(inputSize+outputSize)
-	ml.InitUniform(rnd, layer.weights.Data, variance)
-	return layer
+// hiddenLayer has sparse inputs, weights are by input then by neuron.
+type hiddenLayer struct {
+	size       int
+	weights    *param
+	biases     *param
+	activation ml.IVectorActivationFn
 }
 
-func (layer *Layer) InitWeightsReLU(rnd *rand.Rand) *Layer {
-	var inputSize = layer.weights.Cols
-	var variance = 2.0 / float64(inputSize)
-	ml.InitUniform(rnd, layer.weights.Data, variance)
-	return layer
+func newHiddenLayer(inputSize, size int, activation nnue.Activation) *hiddenLayer {
+	return &hiddenLayer{
+		size:       size,
+		weights:    newParam(inputSize * size),
+		biases:     newParam(size),
+		activation: hiddenActivation(activation),
+	}
 }
 
-func (layer *Layer) InitWeightsCount(rnd *rand.Rand, count float64) *Layer {
-	ml.InitUniform(rnd, layer.weights.Data, 1.0/count)
-	return layer
+func (l *hiddenLayer) initWeights(rnd *rand.Rand) {
+	var inputSize = len(l.weights.values) / l.size
+	initUniform(rnd, l.weights.values, 2.0/float64(inputSize))
 }
 
-func (layer *Layer) Forward(input1 []Neuron, input2 []domain.FeatureInfo) {
-	for outputIndex := range layer.outputs {
-		var x = layer.biases.Data[outputIndex]
-		for inputIndex := range input1 {
-			var inputValue = input1[inputIndex].Activation
-			x += layer.weights.Get(outputIndex, inputIndex) * inputValue
-		}
-		var offsetIndex = len(input1)
-		for _, input := range input2 {
-			var inputIndex = offsetIndex + int(input.Index)
-			var inputValue = float64(input.Value)
-			x += layer.weights.Get(outputIndex, inputIndex) * inputValue
-		}
-		var n = &layer.outputs[outputIndex]
-		n.Activation = layer.activationFn.Sigma(x)
-		n.Prime = layer.activationFn.SigmaPrime(x)
+func (l *hiddenLayer) row(data []float32, input int) []float32 {
+	return data[input*l.size : (input+1)*l.size]
+}
+
+// forward sets pre-activations of hidden neurons.
+func (l *hiddenLayer) forward(pre []float32, features []domain.FeatureInfo) {
+	copy(pre, l.biases.values)
+	for _, feature := range features {
+		addScaled(pre, l.row(l.weights.values, int(feature.Index)), float32(feature.Value))
 	}
 }
 
-func (layer *Layer) Backward(input1 []Neuron, input2 []domain.FeatureInfo) {
-	for inputIndex := range input1 {
-		input1[inputIndex].Error = 0
+// addGradients adds gradients of neurons from lo to hi for errors of every sample.
+// Workers take different neurons, so the only copy of gradients is shared.
+func (l *hiddenLayer) addGradients(features []domain.FeatureInfo, errors []float32, lo, hi int) {
+	addScaled(l.biases.grads[lo:hi], errors[lo:hi], 1)
+	for _, feature := range features {
+		addScaled(l.row(l.weights.grads, int(feature.Index))[lo:hi], errors[lo:hi], float32(feature.Value))
 	}
-	for outputIndex := range layer.outputs {
-		var n = &layer.outputs[outputIndex]
-		var x = n.Error * n.Prime
-		for inputIndex := range input1 {
-			input1[inputIndex].Error += layer.weights.Get(outputIndex, inputIndex) * x
-		}
+}
+
+// denseLayer has weights by input then by neuron.
+// Output layer has no activation, it has output of every bucket and only one of them is computed.
+type denseLayer struct {
+	inputs     int
+	outputs    int
+	weights    *param
+	biases     *param
+	activation ml.IVectorActivationFn
+}
+
+func newDenseLayer(inputs, outputs int, activation ml.IVectorActivationFn) *denseLayer {
+	return &denseLayer{
+		inputs:     inputs,
+		outputs:    outputs,
+		weights:    newParam(inputs * outputs),
+		biases:     newParam(outputs),
+		activation: activation,
 	}
+}
 
-	for outputIndex := range layer.outputs {
-		var n = &layer.outputs[outputIndex]
-		var x = n.Error * n.Prime
-		layer.bGradients.Add(outputIndex, 0, x*1)
+func (l *denseLayer) initWeights(rnd *rand.Rand) {
+	if l.activation == nil {
+		initUniform(rnd, l.weights.values, 2.0/float64(l.inputs+l.outputs))
+	} else {
+		initUniform(rnd, l.weights.values, 2.0/float64(l.inputs))
+	}
+}
 
-		for inputIndex := range input1 {
-			var inputValue = input1[inputIndex].Activation
-			layer.wGradients.Add(outputIndex, inputIndex, x*inputValue)
+// forward sets pre-activations, zero inputs are skipped.
+func (l *denseLayer) forward(pre, input []float32) {
+	copy(pre, l.biases.values)
+	for i, x := range input {
+		if x != 0 {
+			addScaled(pre, l.weights.values[i*l.outputs:(i+1)*l.outputs], x)
 		}
+	}
+}
 
-		var offsetIndex = len(input1)
-		for _, input := range input2 {
-			var inputIndex = offsetIndex + int(input.Index)
-			var inputValue = float64(input.Value)
-			layer.wGradients.Add(outputIndex, inputIndex, x*inputValue)
+// backward sets errors of inputs by errors of pre-activations.
+func (l *denseLayer) backward(inputErrors, errors []float32) {
+	for i := range inputErrors {
+		inputErrors[i] = dot(l.weights.values[i*l.outputs:(i+1)*l.outputs], errors)
+	}
+}
+
+// addGradients adds gradients of inputs from lo to hi for errors of pre-activations of sample,
+// input index l.inputs is biases.
+func (l *denseLayer) addGradients(input, errors []float32, lo, hi int) {
+	for i := lo; i < hi; i++ {
+		if i == l.inputs {
+			addScaled(l.biases.grads, errors, 1)
+		} else if x := input[i]; x != 0 {
+			addScaled(l.weights.grads[i*l.outputs:(i+1)*l.outputs], errors, x)
 		}
 	}
 }
 
-func (layer *Layer) AddGradients(main *Layer) {
-	layer.wGradients.AddTo(&main.wGradients)
-	layer.bGradients.AddTo(&main.bGradients)
+// forwardOutput returns output of bucket.
+func (l *denseLayer) forwardOutput(input []float32, bucket int) float32 {
+	var result = l.biases.values[bucket]
+	for i, x := range input {
+		result += x * l.weights.values[i*l.outputs+bucket]
+	}
+	return result
+}
+
+// backwardOutput sets errors of inputs by error of bucket output.
+func (l *denseLayer) backwardOutput(inputErrors []float32, bucket int, err float32) {
+	for i := range inputErrors {
+		inputErrors[i] = l.weights.values[i*l.outputs+bucket] * err
+	}
+}
+
+func (l *denseLayer) applyGradients() {
+	l.weights.apply(0, len(l.weights.values))
+	l.biases.apply(0, len(l.biases.values))
+}
+
+// hiddenActivation converts activation of network file to trainer activation.
+func hiddenActivation(activation nnue.Activation) ml.IVectorActivationFn {
+	var activationFn, err = ml.NewActivation(activation.String())
+	if err != nil {
+		panic(err)
+	}
+	return activationFn
+}
+
+func addScaled(dst, src []float32, scale float32) {
+	src = src[:len(dst)]
+	for i := range dst {
+		dst[i] += src[i] * scale
+	}
 }
 
-func (layer *Layer) ApplyGradients() {
-	layer.wGradients.Apply(&layer.weights)
-	layer.bGradients.Apply(&layer.biases)
+func dot(a, b []float32) float32 {
+	b = b[:len(a)]
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
 }
@@ -1,139 +1,338 @@
 package train
 
 import (
-	"encoding/binary"
-	"io"
-	"math"
 	"math/rand"
-	"os"
+	"sync"
+	"sync/atomic"
 
+	"github.com/ChizhovVadim/CounterGo/internal/domain"
 	"github.com/ChizhovVadim/CounterGo/internal/ml"
+	nnue "github.com/ChizhovVadim/CounterGo/pkg/eval/nnue"
 )
 
+// Model has sparse hidden layer and stack of dense layers, the last layer has output of every bucket.
+// Perspective model computes hidden layer for side to move and opponent with the same weights,
+// its output predicts result for side to move. Forward returns result from white point of view.
 type Model struct {
-	layer1 *Layer
-	layer2 *Layer
+	arch        nnue.Architecture
+	layout      nnue.PerspectiveLayout
+	hidden      *hiddenLayer
+	layers      []*denseLayer
+	activations []nnue.Activation
+	workers     []*worker
+	// Hidden errors, inputs and errors of dense layers of every sample of batch.
+	// Gradients are added from them after batch in order of samples, so they do not depend on number of workers.
+	hiddenErrors []float32
+	layerInputs  [][]float32
+	layerErrors  [][]float32
+	// OutputScale converts output logit to centipawns in network files.
+	OutputScale float64
 }
 
-func NewModel(inputSize, hiddenSize int) *Model {
-	return &Model{
-		layer1: NewLayer(
-			inputSize,
-			make([]Neuron, hiddenSize),
-			&ml.ReLuActivation{}),
-		layer2: NewLayer(
-			hiddenSize,
-			make([]Neuron, 1),
-			&ml.SigmoidActivation{}),
+// worker keeps layer values of one sample.
+// values[0] is hidden layer of every side, values[i+1] is output of dense layer i.
+type worker struct {
+	pre    [][]float32
+	values [][]float32
+	errors [][]float32
+	cost   float64
+}
+
+// NewModel creates network with layers of layerSizes, the last size is number of output buckets.
+// Activations are of every layer except output.
+func NewModel(inputSize int, layerSizes []int, activations []nnue.Activation) *Model {
+	return newModel(nnue.ArchAbsolute, nnue.PerspectiveLayout{}, inputSize, layerSizes, activations)
+}
+
+func newModel(arch nnue.Architecture, layout nnue.PerspectiveLayout, inputSize int, layerSizes []int, activations []nnue.Activation) *Model {
+	var m = &Model{
+		arch:        arch,
+		layout:      layout,
+		hidden:      newHiddenLayer(inputSize, layerSizes[0], activations[0]),
+		activations: activations,
 	}
+	var inputs = m.sides() * layerSizes[0]
+	for i, size := range layerSizes[1:] {
+		var activation ml.IVectorActivationFn
+		if i+1 < len(activations) {
+			activation = hiddenActivation(activations[i+1])
+		}
+		m.layers = append(m.layers, newDenseLayer(inputs, size, activation))
+		inputs = size
+	}
+	return m
+}
+
+// sides is the number of hidden layer copies.
+func (m *Model) sides() int {
+	if m.arch == nnue.ArchPerspective {
+		return 2
+	}
+	return 1
+}
+
+func (m *Model) sideFeatures(input *Input, side int) []domain.FeatureInfo {
+	if side == 0 {
+		return input.Features
+	}
+	return input.Opponent
+}
+
+func (m *Model) output() *denseLayer {
+	return m.layers[len(m.layers)-1]
+}
+
+func (m *Model) newWorker() *worker {
+	var w = &worker{}
+	var size = m.sides() * m.hidden.size
+	w.pre = append(w.pre, make([]float32, size))
+	w.values = append(w.values, make([]float32, size))
+	w.errors = append(w.errors, make([]float32, size))
+	for _, layer := range m.layers {
+		w.pre = append(w.pre, make([]float32, layer.outputs))
+		w.values = append(w.values, make([]float32, layer.outputs))
+		w.errors = append(w.errors, make([]float32, layer.outputs))
+	}
+	return w
 }
 
 func (m *Model) InitWeights(rnd *rand.Rand) {
-	m.layer1.InitWeightsReLU(rnd) // ненулевых входных признаков не более 32 (кол во фигур на доске)
-	m.layer2.InitWeightsSigmoid(rnd)
+	m.hidden.initWeights(rnd) // ненулевых входных признаков не более 32 (кол во фигур на доске)
+	for _, layer := range m.layers {
+		layer.initWeights(rnd)
+	}
 }
 
+// Forward is not safe for concurrent use, batches are evaluated by TrainBatch and AverageCost.
 func (m *Model) Forward(input *Input) float64 {
-	m.layer1.Forward(nil, input.Features)
-	m.layer2.Forward(m.layer1.outputs, nil)
-	return m.layer2.outputs[0].Activation
+	return m.predict(m.getWorkers(1)[0], input)
+}
+
+// predict returns result from white point of view.
+func (m *Model) predict(w *worker, input *Input) float64 {
+	var predicted = ml.Sigmoid(float64(m.forward(w, input)))
+	if m.arch == nnue.ArchPerspective && !input.WhiteMove {
+		predicted = 1 - predicted
+	}
+	return predicted
+}
+
+// forward returns output logit of bucket.
+func (m *Model) forward(w *worker, input *Input) float32 {
+	var size = m.hidden.size
+	for side := 0; side < m.sides(); side++ {
+		var pre = w.pre[0][side*size : (side+1)*size]
+		m.hidden.forward(pre, m.sideFeatures(input, side))
+		m.hidden.activation.Forward(w.values[0][side*size:(side+1)*size], pre)
+	}
+	var last = len(m.layers) - 1
+	for i, layer := range m.layers[:last] {
+		layer.forward(w.pre[i+1], w.values[i])
+		layer.activation.Forward(w.values[i+1], w.pre[i+1])
+	}
+	return m.output().forwardOutput(w.values[last], outputBucket(input, m.output()))
 }
 
-func (m *Model) Train(sample *Sample, cost ml.IModelCost) {
-	predicted := m.Forward(&sample.input)
-	m.layer2.outputs[0].Error = cost.CostPrime(predicted, float64(sample.target))
-	// back propagation
-	m.layer2.Backward(m.layer1.outputs, nil)
-	m.layer1.Backward(nil, sample.input.Features)
+// backward keeps inputs and errors of pre-activations of layers of sample index.
+func (m *Model) backward(w *worker, input *Input, outputError float32, index int) {
+	var last = len(m.layers) - 1
+	var output = m.output()
+	var outputErrors = m.layerErrors[last][index*output.outputs : (index+1)*output.outputs]
+	var bucket = outputBucket(input, output)
+	for i := range outputErrors {
+		outputErrors[i] = 0
+	}
+	outputErrors[bucket] = outputError
+	output.backwardOutput(w.errors[last], bucket, outputError)
+	for i := last - 1; i >= 0; i-- {
+		var layer = m.layers[i]
+		// errors of pre-activations are kept in place of errors of outputs
+		layer.activation.Backward(w.errors[i+1], w.pre[i+1], w.errors[i+1])
+		layer.backward(w.errors[i], w.errors[i+1])
+		copy(m.layerErrors[i][index*layer.outputs:], w.errors[i+1])
+	}
+	for i, layer := range m.layers {
+		copy(m.layerInputs[i][index*layer.inputs:], w.values[i])
+	}
+	var size = m.sides() * m.hidden.size
+	m.hidden.activation.Backward(m.hiddenErrors[index*size:(index+1)*size], w.pre[0], w.errors[0])
 }
 
-func (m *Model) ApplyGradients() {
-	m.layer1.ApplyGradients()
-	m.layer2.ApplyGradients()
+// TrainBatch computes gradients of samples by concurrency workers and applies them.
+func (m *Model) TrainBatch(samples []Sample, cost ml.IModelCost, concurrency int) {
+	var workers = m.getWorkers(concurrency)
+	m.addGradients(workers, samples, cost)
+	m.applyGradients(len(workers))
 }
 
-func (m *Model) Clone() IModel {
-	return &Model{
-		layer1: m.layer1.ThreadCopy(),
-		layer2: m.layer2.ThreadCopy(),
+// addGradients adds gradients of cost of every sample to layers.
+func (m *Model) addGradients(workers []*worker, samples []Sample, cost ml.IModelCost) {
+	m.resizeBatch(len(samples))
+	forEachSample(workers, samples, func(w *worker, sample *Sample, index int) {
+		var output = ml.Sigmoid(float64(m.forward(w, &sample.input)))
+		var predicted, sign = output, 1.0
+		if m.arch == nnue.ArchPerspective && !sample.input.WhiteMove {
+			predicted, sign = 1-output, -1.0
+		}
+		var outputError = sign * cost.CostPrime(predicted, float64(sample.target)) * output * (1 - output)
+		m.backward(w, &sample.input, float32(outputError), index)
+	})
+	// Dense layer gradients are split by inputs between workers, the last row is biases.
+	for i, layer := range m.layers {
+		var layer, inputs, errors = layer, m.layerInputs[i], m.layerErrors[i]
+		forEachRange(layer.inputs+1, len(workers), func(lo, hi int) {
+			for sample := range samples {
+				layer.addGradients(inputs[sample*layer.inputs:(sample+1)*layer.inputs], errors[sample*layer.outputs:(sample+1)*layer.outputs], lo, hi)
+			}
+		})
 	}
+	// Hidden layer gradients are split by neurons between workers.
+	var size = m.sides() * m.hidden.size
+	forEachRange(m.hidden.size, len(workers), func(lo, hi int) {
+		for i := range samples {
+			var errors = m.hiddenErrors[i*size : (i+1)*size]
+			for side := 0; side < m.sides(); side++ {
+				m.hidden.addGradients(m.sideFeatures(&samples[i].input, side), errors[side*m.hidden.size:(side+1)*m.hidden.size], lo, hi)
+			}
+		}
+	})
 }
 
-func (m *Model) AddGradients(abstractMainModel IModel) {
-	var mainModel = abstractMainModel.(*Model)
-	if m == mainModel {
+func (m *Model) resizeBatch(samples int) {
+	var size = m.sides() * m.hidden.size
+	if len(m.hiddenErrors) >= samples*size {
 		return
 	}
-	m.layer1.AddGradients(mainModel.layer1)
-	m.layer2.AddGradients(mainModel.layer2)
+	m.hiddenErrors = make([]float32, samples*size)
+	m.layerInputs = m.layerInputs[:0]
+	m.layerErrors = m.layerErrors[:0]
+	for _, layer := range m.layers {
+		m.layerInputs = append(m.layerInputs, make([]float32, samples*layer.inputs))
+		m.layerErrors = append(m.layerErrors, make([]float32, samples*layer.outputs))
+	}
 }
 
-func (m *Model) LoadWeights(path string) error {
-	f, err := os.Open(path)
-	if err != nil {
-		return err
+// applyGradients updates weights by Adam and resets gradients.
+func (m *Model) applyGradients(concurrency int) {
+	forEachRange(m.hidden.size, concurrency, func(lo, hi int) {
+		m.hidden.biases.apply(lo, hi)
+		for input := 0; input < len(m.hidden.weights.values)/m.hidden.size; input++ {
+			var offset = input * m.hidden.size
+			m.hidden.weights.apply(offset+lo, offset+hi)
+		}
+	})
+	for _, layer := range m.layers {
+		layer.applyGradients()
 	}
-	defer f.Close()
+}
 
-	var data = [...][]float64{
-		m.layer1.weights.Data,
-		m.layer1.biases.Data,
-		m.layer2.weights.Data,
-		m.layer2.biases.Data,
+// AverageCost evaluates samples by concurrency workers.
+func (m *Model) AverageCost(samples []Sample, cost ml.IModelCost, concurrency int) float64 {
+	var workers = m.getWorkers(concurrency)
+	for _, w := range workers {
+		w.cost = 0
 	}
-	for i := range data {
-		var err = readSlice(f, data[i])
-		if err != nil {
-			return err
-		}
+	forEachSample(workers, samples, func(w *worker, sample *Sample, index int) {
+		w.cost += cost.Cost(m.predict(w, &sample.input), float64(sample.target))
+	})
+	var totalCost float64
+	for _, w := range workers {
+		totalCost += w.cost
 	}
-	return nil
+	return totalCost / float64(len(samples))
 }
 
-func (m *Model) SaveWeights(path string) error {
-	f, err := os.Create(path)
-	if err != nil {
-		return err
+func (m *Model) getWorkers(concurrency int) []*worker {
+	for len(m.workers) < concurrency {
+		m.workers = append(m.workers, m.newWorker())
 	}
-	defer f.Close()
+	return m.workers[:concurrency]
+}
 
-	var data = [...][]float64{
-		m.layer1.weights.Data,
-		m.layer1.biases.Data,
-		m.layer2.weights.Data,
-		m.layer2.biases.Data,
+// forEachSample calls f for every sample by workers.
+func forEachSample(workers []*worker, samples []Sample, f func(w *worker, sample *Sample, index int)) {
+	var index int32 = -1
+	var wg = &sync.WaitGroup{}
+	for _, w := range workers {
+		wg.Add(1)
+		go func(w *worker) {
+			defer wg.Done()
+			for {
+				var i = int(atomic.AddInt32(&index, 1))
+				if i >= len(samples) {
+					break
+				}
+				f(w, &samples[i], i)
+			}
+		}(w)
 	}
-	for i := range data {
-		var err = writeSlice(f, data[i])
-		if err != nil {
-			return err
+	wg.Wait()
+}
+
+// forEachRange splits [0, size) between workers.
+func forEachRange(size, workers int, f func(lo, hi int)) {
+	var wg = &sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		var lo, hi = size * i / workers, size * (i + 1) / workers
+		if lo == hi {
+			continue
 		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f(lo, hi)
+		}()
 	}
-	return nil
+	wg.Wait()
 }
 
-func readSlice(f io.Reader, data []float64) error {
-	var buf [4]byte
-	for i := range data {
-		_, err := io.ReadFull(f, buf[:])
-		if err != nil {
-			return err
-		}
-		var val = math.Float32frombits(binary.LittleEndian.Uint32(buf[:]))
-		data[i] = float64(val)
+// LoadModel creates model of network file topology with its weights.
+func LoadModel(path string) (*Model, error) {
+	var net, err = nnue.LoadNetFile(path)
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	var m = newModel(net.Arch, net.Layout, net.InputSize, net.LayerSizes, net.Activations)
+	m.OutputScale = float64(net.OutputScale)
+	err = m.LoadWeights(path)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
 }
 
-func writeSlice(f io.Writer, data []float64) error {
-	var buf [4]byte
-	for i := range data {
-		var val = float32(data[i])
-		binary.LittleEndian.PutUint32(buf[:], math.Float32bits(val))
-		_, err := f.Write(buf[:])
-		if err != nil {
-			return err
-		}
+func (m *Model) LoadWeights(path string) error {
+	return loadNet(path, m.header(), m.data())
+}
+
+func (m *Model) SaveWeights(path string) error {
+	return saveNet(path, m.header(), m.data())
+}
+
+func (m *Model) header() nnue.NetHeader {
+	var layerSizes = []int{m.hidden.size}
+	for _, layer := range m.layers {
+		layerSizes = append(layerSizes, layer.outputs)
+	}
+	return nnue.NetHeader{
+		Arch:        m.arch,
+		Layout:      m.layout,
+		InputSize:   len(m.hidden.weights.values) / m.hidden.size,
+		LayerSizes:  layerSizes,
+		Activations: m.activations,
+		OutputScale: float32(m.OutputScale),
 	}
-	return nil
+}
+
+func (m *Model) data() [][]float32 {
+	var result = [][]float32{m.hidden.weights.values, m.hidden.biases.values}
+	for _, layer := range m.layers {
+		result = append(result, layer.weights.values, layer.biases.values)
+	}
+	return result
+}
+
+// outputBucket selects output neuron of layer by number of pieces.
+func outputBucket(input *Input, layer *denseLayer) int {
+	return nnue.OutputBucket(int(input.PieceCount), layer.outputs)
 }
@@ -0,0 +1,102 @@
+package train
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/ChizhovVadim/CounterGo/internal/ml"
+	"github.com/ChizhovVadim/CounterGo/pkg/common"
+	nnue "github.com/ChizhovVadim/CounterGo/pkg/eval/nnue"
+)
+
+// testModels are tiny networks of every architecture with dense layers and output buckets.
+func testModels() map[string]func() (*Model, IFeatureProvider) {
+	var activations = []nnue.Activation{nnue.ActReLU, nnue.ActSCReLU}
+	return map[string]func() (*Model, IFeatureProvider){
+		"absolute": func() (*Model, IFeatureProvider) {
+			return NewModel(768, []int{8, 4, 2}, activations), &Feature768Provider{}
+		},
+		"perspective": func() (*Model, IFeatureProvider) {
+			return NewPerspectiveModel(nnue.NoKingBuckets, []int{8, 4, 2}, activations),
+				&FeaturePerspectiveProvider{Layout: nnue.NoKingBuckets}
+		},
+	}
+}
+
+// testSamples are positions of random games with random targets.
+func testSamples(rnd *rand.Rand, fp IFeatureProvider, count int) []Sample {
+	var samples []Sample
+	var pos, _ = common.NewPositionFromFEN(common.InitialPositionFen)
+	for len(samples) < count {
+		var moves = pos.GenerateLegalMoves()
+		if len(moves) == 0 {
+			pos, _ = common.NewPositionFromFEN(common.InitialPositionFen)
+			continue
+		}
+		var child common.Position
+		pos.MakeMove(moves[rnd.Intn(len(moves))], &child)
+		pos = child
+		samples = append(samples, Sample{
+			input:  fp.ComputeFeatures(&pos),
+			target: float32(rnd.Float64()),
+		})
+	}
+	return samples
+}
+
+func TestTrainBatchGradients(t *testing.T) {
+	for name, build := range testModels() {
+		var model, fp = build()
+		model.InitWeights(rand.New(rand.NewSource(1)))
+		var samples = testSamples(rand.New(rand.NewSource(2)), fp, 16)
+		var cost = &ml.CrossEntropyCost{}
+		model.addGradients(model.getWorkers(1), samples, cost)
+
+		var totalCost = func() float64 {
+			return model.AverageCost(samples, cost, 1) * float64(len(samples))
+		}
+		const eps = 1e-3
+		var grads = [][]float32{model.hidden.weights.grads, model.hidden.biases.grads}
+		for _, layer := range model.layers {
+			grads = append(grads, layer.weights.grads, layer.biases.grads)
+		}
+		for param, values := range model.data() {
+			for i := range values {
+				var value = values[i]
+				values[i] = value + eps
+				var plus = totalCost()
+				values[i] = value - eps
+				var minus = totalCost()
+				values[i] = value
+				var numeric = (plus - minus) / (2 * eps)
+				var analytic = float64(grads[param][i])
+				if math.Abs(numeric-analytic) > 1e-3+1e-2*math.Abs(analytic) {
+					t.Fatalf("%v: param %v index %v: gradient %v, finite difference %v", name, param, i, analytic, numeric)
+				}
+			}
+		}
+	}
+}
+
+func TestTrainBatchConcurrency(t *testing.T) {
+	for name, build := range testModels() {
+		var single, fp = build()
+		var parallel, _ = build()
+		single.InitWeights(rand.New(rand.NewSource(1)))
+		parallel.InitWeights(rand.New(rand.NewSource(1)))
+		var samples = testSamples(rand.New(rand.NewSource(2)), fp, 1000)
+		var cost = &ml.MSECost{}
+		single.TrainBatch(samples, cost, 1)
+		parallel.TrainBatch(samples, cost, 4)
+
+		var parallelData = parallel.data()
+		for param, values := range single.data() {
+			for i := range values {
+				if values[i] != parallelData[param][i] {
+					t.Fatalf("%v: param %v index %v: weight %v by 1 worker, %v by 4 workers", name, param, i, values[i], parallelData[param][i])
+				}
+			}
+		}
+	}
+}
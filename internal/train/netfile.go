@@ -0,0 +1,36 @@
+package train
+
+import (
+	nnue "github.com/ChizhovVadim/CounterGo/pkg/eval/nnue"
+)
+
+// saveNet writes weights of layers in network file format.
+// data are weights and biases of every layer in the order of nnue.Net.
+func saveNet(path string, header nnue.NetHeader, data [][]float32) error {
+	var net, err = nnue.NewNet(header)
+	if err != nil {
+		return err
+	}
+	for i := range net.Layers {
+		copy(net.Layers[i].Weights, data[2*i])
+		copy(net.Layers[i].Biases, data[2*i+1])
+	}
+	return nnue.SaveNetFile(path, net)
+}
+
+// loadNet reads weights of layers and checks that file has topology of model.
+func loadNet(path string, header nnue.NetHeader, data [][]float32) error {
+	var net, err = nnue.LoadNetFile(path)
+	if err != nil {
+		return err
+	}
+	err = net.CheckTopology(&header)
+	if err != nil {
+		return err
+	}
+	for i := range net.Layers {
+		copy(data[2*i], net.Layers[i].Weights)
+		copy(data[2*i+1], net.Layers[i].Biases)
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package train
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/ChizhovVadim/CounterGo/internal/dataset"
+	"github.com/ChizhovVadim/CounterGo/internal/ml"
+)
+
+func loadPackedDataset(
+	featureProvider func() IFeatureProvider,
+	path string,
+	filter *dataset.Filter,
+	sigmoidScale float64,
+	maxSize int,
+	concurrency int,
+	mirrorPos bool,
+) ([]Sample, error) {
+	var datasetReady = make(chan struct{})
+	var positions = make(chan []dataset.PackedPosition, 16)
+	var results = make(chan []Sample, 16)
+
+	g, ctx := errgroup.WithContext(context.Background())
+
+	g.Go(func() error {
+		defer close(positions)
+		return dataset.LoadPackedPositions(ctx, path, datasetReady, positions)
+	})
+
+	var res []Sample
+	g.Go(func() error {
+		var samples, err = collectResult(ctx, results, maxSize, datasetReady)
+		res = samples
+		return err
+	})
+
+	var wg = &sync.WaitGroup{}
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		g.Go(func() error {
+			defer wg.Done()
+			var fp = featureProvider()
+			for chunk := range positions {
+				var samples, err = unpackSamples(chunk, nil, fp, filter, sigmoidScale, mirrorPos)
+				if err != nil {
+					return err
+				}
+				results <- samples
+			}
+			return nil
+		})
+	}
+
+	g.Go(func() error {
+		wg.Wait()
+		close(results)
+		return nil
+	})
+
+	var err = g.Wait()
+	return res, err
+}
+
+func unpackSamples(
+	positions []dataset.PackedPosition,
+	samples []Sample,
+	featureProvider IFeatureProvider,
+	filter *dataset.Filter,
+	sigmoidScale float64,
+	mirrorPos bool,
+) ([]Sample, error) {
+	for i := range positions {
+		var pos, gameResult, err = positions[i].Unpack()
+		if err != nil {
+			return nil, err
+		}
+		if !filter.Accept(&pos) {
+			continue
+		}
+		samples = appendSamples(samples, featureProvider, &pos, gameResult, sigmoidScale, mirrorPos)
+	}
+	return samples, nil
+}
+
+// TrainPacked trains on a binary dataset file without loading it into memory.
+// The file is read once per epoch, so it should be shuffled on disk beforehand.
+// The first positions of the file are the validation set.
+func TrainPacked(
+	path string,
+	featureProvider func() IFeatureProvider,
+	filter *dataset.Filter,
+	sigmoidScale float64,
+	mirrorPos bool,
+	mainModel IModel,
+	cost ml.IModelCost,
+	config Config,
+) error {
+	total, err := dataset.PackedFilePositions(path)
+	if err != nil {
+		return err
+	}
+	// The window is shuffled in memory, which is enough for a file shuffled on disk.
+	const windowBatches = 16
+	var windows = dataset.PackedWindows{
+		Path:           path,
+		ValidationSize: min(500_000, total/5),
+		WindowSize:     windowBatches * BatchSize,
+		Workers:        config.Concurrency,
+	}
+
+	var providers = make([]IFeatureProvider, config.Concurrency)
+	for i := range providers {
+		providers[i] = featureProvider()
+	}
+	var parts = make([][]Sample, config.Concurrency)
+	var decode = func(worker int, positions []dataset.PackedPosition) error {
+		var samples, err = unpackSamples(positions, parts[worker], providers[worker], filter, sigmoidScale, mirrorPos)
+		parts[worker] = samples
+		return err
+	}
+	// takeParts moves decoded samples of workers to samples.
+	var takeParts = func(samples []Sample) []Sample {
+		for i := range parts {
+			samples = append(samples, parts[i]...)
+			parts[i] = parts[i][:0]
+		}
+		return samples
+	}
+
+	err = windows.Validation(decode)
+	if err != nil {
+		return err
+	}
+	var validation = takeParts(nil)
+	filter.LogStats()
+	// training positions that repeat validation ones are dropped by dedup in every epoch
+	filter.HoldSeen()
+
+	var rnd = rand.New(rand.NewSource(0))
+	var pending []Sample
+	return trainEpochs(validation, func(onBatch func(batch []Sample)) error {
+		filter.Reset()
+		defer filter.LogStats()
+		// Samples that do not fill a batch are carried to the next window.
+		var err = windows.Walk(decode, func() error {
+			pending = takeParts(pending)
+			shuffle(rnd, pending)
+			var size = len(pending) / BatchSize * BatchSize
+			for i := 0; i < size; i += BatchSize {
+				onBatch(pending[i : i+BatchSize])
+			}
+			pending = append(pending[:0], pending[size:]...)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if len(pending) != 0 {
+			onBatch(pending)
+			pending = pending[:0]
+		}
+		return nil
+	}, mainModel, cost, config)
+}
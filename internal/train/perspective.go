@@ -0,0 +1,11 @@
+package train
+
+import (
+	nnue "github.com/ChizhovVadim/CounterGo/pkg/eval/nnue"
+)
+
+// NewPerspectiveModel creates network with hidden layer shared by side to move and opponent.
+// The last size of layerSizes is number of output buckets, activations are of every layer except output.
+func NewPerspectiveModel(layout nnue.PerspectiveLayout, layerSizes []int, activations []nnue.Activation) *Model {
+	return newModel(nnue.ArchPerspective, layout, layout.InputSize(), layerSizes, activations)
+}
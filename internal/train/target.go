@@ -0,0 +1,99 @@
+package train
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ChizhovVadim/CounterGo/internal/math"
+)
+
+// LambdaPhase is lambda of the next Epochs epochs.
+type LambdaPhase struct {
+	Lambda float64
+	Epochs int
+}
+
+// LambdaSchedule gives lambda of every epoch: target is lambda*sigmoid(score*K) + (1-lambda)*result.
+// The last phase lasts till the end of training.
+type LambdaSchedule []LambdaPhase
+
+// ParseLambdaSchedule accepts phases lambda:epochs separated by comma, epochs of the last phase may be omitted.
+// For example "1.0:5,0.7" is lambda 1 for 5 epochs and 0.7 after them.
+func ParseLambdaSchedule(s string) (LambdaSchedule, error) {
+	var schedule LambdaSchedule
+	var phases = strings.Split(s, ",")
+	for i, phase := range phases {
+		var fields = strings.Split(strings.TrimSpace(phase), ":")
+		if len(fields) > 2 || len(fields) == 1 && i != len(phases)-1 {
+			return nil, fmt.Errorf("bad lambda phase %v", phase)
+		}
+		lambda, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil || lambda < 0 || lambda > 1 {
+			return nil, fmt.Errorf("bad lambda %v", fields[0])
+		}
+		var epochs int
+		if len(fields) == 2 {
+			epochs, err = strconv.Atoi(fields[1])
+			if err != nil || epochs <= 0 {
+				return nil, fmt.Errorf("bad lambda phase epochs %v", fields[1])
+			}
+		}
+		schedule = append(schedule, LambdaPhase{Lambda: lambda, Epochs: epochs})
+	}
+	return schedule, nil
+}
+
+// Lambda of epoch, epochs are numbered from 1.
+func (s LambdaSchedule) Lambda(epoch int) float64 {
+	for _, phase := range s {
+		if epoch <= phase.Epochs {
+			return phase.Lambda
+		}
+		epoch -= phase.Epochs
+	}
+	return s[len(s)-1].Lambda
+}
+
+func (s LambdaSchedule) String() string {
+	var phases []string
+	for _, phase := range s {
+		var text = strconv.FormatFloat(phase.Lambda, 'g', -1, 64)
+		if phase.Epochs != 0 {
+			text += ":" + strconv.Itoa(phase.Epochs)
+		}
+		phases = append(phases, text)
+	}
+	return strings.Join(phases, ",")
+}
+
+// searchTarget is win probability of white by search score.
+func searchTarget(
+	wstm bool,
+	scoreMate int,
+	scoreCentipawns int,
+	sigmoidScale float64,
+) float64 {
+	var targetBySearch float64
+	if scoreMate != 0 {
+		if scoreMate > 0 {
+			targetBySearch = 1
+		} else {
+			targetBySearch = 0
+		}
+	} else {
+		targetBySearch = math.Sigmoid(sigmoidScale * float64(scoreCentipawns))
+	}
+	if !wstm {
+		targetBySearch = 1 - targetBySearch
+	}
+	return targetBySearch
+}
+
+// setTargets blends search score and game result of samples by lambda.
+func setTargets(samples []Sample, lambda float64) {
+	for i := range samples {
+		var sample = &samples[i]
+		sample.target = float32(lambda)*sample.score + float32(1-lambda)*sample.result
+	}
+}
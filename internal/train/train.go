@@ -6,52 +6,104 @@ import (
 	"math/rand"
 	"os"
 	"path/filepath"
-	"sync"
-	"sync/atomic"
+	"time"
 
 	"github.com/ChizhovVadim/CounterGo/internal/ml"
 )
 
+const BatchSize = 16384
+
 func Train(
 	samples []Sample,
-	epochs int,
 	mainModel IModel,
 	cost ml.IModelCost,
-	concurrency int,
-	netFolderPath string,
+	config Config,
 ) error {
-	log.Println("Train started")
-	defer log.Println("Train finished")
-
-	err := os.MkdirAll(netFolderPath, os.ModePerm)
-	if err != nil {
-		return err
-	}
-
 	var validationSize = min(500_000, len(samples)/5)
 	var validation = samples[:validationSize]
 	var training = samples[validationSize:]
 
-	const BatchSize = 16384
-	var models = make([]IModel, concurrency)
-	models[0] = mainModel
-	for i := 1; i < len(models); i++ {
-		models[i] = mainModel.Clone()
-	}
-
 	var rnd = rand.New(rand.NewSource(0))
-	for epoch := 1; epoch <= epochs; epoch++ {
+	return trainEpochs(validation, func(onBatch func(batch []Sample)) error {
 		shuffle(rnd, training)
 		for i := 0; i+BatchSize <= len(training); i += BatchSize {
-			var batch = training[i : i+BatchSize]
-			trainBatch(batch, models, cost)
-			applyGradients(models)
+			onBatch(training[i : i+BatchSize])
+		}
+		return nil
+	}, mainModel, cost, config)
+}
+
+// trainEpochs calls epoch to produce the training batches of every epoch.
+func trainEpochs(
+	validation []Sample,
+	epoch func(onBatch func(batch []Sample)) error,
+	mainModel IModel,
+	cost ml.IModelCost,
+	config Config,
+) error {
+	log.Println("Train started")
+	defer log.Println("Train finished")
+
+	if config.NetFolderPath != "" {
+		var err = os.MkdirAll(config.NetFolderPath, os.ModePerm)
+		if err != nil {
+			return err
+		}
+	}
+	if config.ResumePath != "" {
+		var err = mainModel.LoadWeights(config.ResumePath)
+		if err != nil {
+			return fmt.Errorf("resume %v: %w", config.ResumePath, err)
+		}
+		log.Println("Resumed", config.ResumePath, "epoch", config.StartEpoch)
+	}
+
+	for epochNumber := config.StartEpoch + 1; epochNumber <= config.Epochs; epochNumber++ {
+		var epochLambda = config.Lambda.Lambda(epochNumber)
+		var start = time.Now()
+		var positions int
+		var err = epoch(func(batch []Sample) {
+			setTargets(batch, epochLambda)
+			mainModel.TrainBatch(batch, cost, config.Concurrency)
+			positions += len(batch)
+		})
+		if err != nil {
+			return err
 		}
-		log.Printf("Finished Epoch %v\n", epoch)
-		validationCost := calcAverageCost(validation, models, cost)
+		var elapsed = time.Since(start)
+		log.Printf("Finished Epoch %v\n", epochNumber)
+		log.Println("epoch", epochNumber,
+			"positions", positions,
+			"time", elapsed.Round(time.Second),
+			"positions/s", int(float64(positions)/elapsed.Seconds()),
+			"lambda", epochLambda)
+		setTargets(validation, epochLambda)
+		validationCost := mainModel.AverageCost(validation, cost, config.Concurrency)
 		log.Printf("Current validation cost is: %f\n", validationCost)
-		if netFolderPath != "" {
-			var err = mainModel.SaveWeights(buildNetPath(netFolderPath, epoch, validationCost))
+		if config.NetFolderPath != "" {
+			var netPath = buildNetPath(config.NetFolderPath, epochNumber, validationCost)
+			var err = mainModel.SaveWeights(netPath)
+			if err != nil {
+				return err
+			}
+			if config.Experiment != nil {
+				err = saveNetJson(netPath, ".json", config.Experiment)
+				if err != nil {
+					return err
+				}
+			}
+			var report = Report{
+				Epoch:          epochNumber,
+				Lambda:         epochLambda,
+				ValidationCost: validationCost,
+			}
+			if config.Evaluate != nil {
+				report.Evaluation, err = config.Evaluate(netPath)
+				if err != nil {
+					return err
+				}
+			}
+			err = saveNetJson(netPath, ".report.json", report)
 			if err != nil {
 				return err
 			}
@@ -67,62 +119,6 @@ func shuffle(rnd *rand.Rand, training []Sample) {
 	})
 }
 
-func trainBatch(samples []Sample, models []IModel, cost ml.IModelCost) {
-	var index int32 = -1
-	var wg = &sync.WaitGroup{}
-	for i := range models {
-		wg.Add(1)
-		go func(m IModel) {
-			defer wg.Done()
-			for {
-				var i = int(atomic.AddInt32(&index, 1))
-				if i >= len(samples) {
-					break
-				}
-				sample := &samples[i]
-				m.Train(sample, cost)
-			}
-		}(models[i])
-	}
-	wg.Wait()
-}
-
-func applyGradients(models []IModel) {
-	var mainModel = models[0]
-	for i := 1; i < len(models); i++ {
-		models[i].AddGradients(mainModel)
-	}
-	mainModel.ApplyGradients()
-}
-
-func calcAverageCost(samples []Sample, models []IModel, cost ml.IModelCost) float64 {
-	var index int32 = -1
-	var wg = &sync.WaitGroup{}
-	var totalCost float64
-	var mu = &sync.Mutex{}
-	for i := range models {
-		wg.Add(1)
-		go func(model IModel) {
-			defer wg.Done()
-			var localCost float64
-			for {
-				var i = int(atomic.AddInt32(&index, 1))
-				if i >= len(samples) {
-					break
-				}
-				var sample = &samples[i]
-				localCost += cost.Cost(model.Forward(&sample.input), float64(sample.target))
-			}
-			mu.Lock()
-			totalCost += localCost
-			mu.Unlock()
-		}(models[i])
-	}
-	wg.Wait()
-	averageCost := totalCost / float64(len(samples))
-	return averageCost
-}
-
 func min(a, b int) int {
 	if a < b {
 		return a
@@ -8,13 +8,22 @@ import (
 	"github.com/ChizhovVadim/CounterGo/pkg/common"
 )
 
+// Sample has search score and game result as win probability of white.
+// Target is their blend by lambda of epoch.
 type Sample struct {
 	input  Input
+	score  float32
+	result float32
 	target float32
 }
 
+// Input of perspective networks has features of side to move in Features.
+// PieceCount selects output bucket.
 type Input struct {
-	Features []domain.FeatureInfo
+	Features   []domain.FeatureInfo
+	Opponent   []domain.FeatureInfo
+	WhiteMove  bool
+	PieceCount int8
 }
 
 type IFeatureProvider interface {
@@ -27,8 +36,7 @@ type IModel interface {
 	LoadWeights(path string) error
 	SaveWeights(path string) error
 	Forward(input *Input) float64
-	Train(sample *Sample, cost ml.IModelCost)
-	ApplyGradients()
-	AddGradients(mainModel IModel) //for concurrent learning
-	Clone() IModel                 //for concurrent learning
+	// TrainBatch computes gradients of samples by concurrency workers and applies them.
+	TrainBatch(samples []Sample, cost ml.IModelCost, concurrency int)
+	AverageCost(samples []Sample, cost ml.IModelCost, concurrency int) float64
 }
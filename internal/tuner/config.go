@@ -0,0 +1,95 @@
+package tuner
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/ChizhovVadim/CounterGo/internal/domain"
+	"github.com/ChizhovVadim/CounterGo/internal/ml"
+)
+
+type Config struct {
+	Epochs          int
+	Concurrency     int
+	Optimizer       string  // adam, adagrad or sgd
+	LearningRate    float64 // gradients are summed over batch, sgd needs a much smaller rate
+	Momentum        float64 // sgd only
+	Schedule        string  // constant, step, exp or cosine
+	DecayRate       float64
+	DecayEpochs     int     // step schedule only
+	ValidationRatio float64 // part of dataset held out for validation
+	Patience        int     // stop after epochs without validation improvement, 0 disables
+	GroupRates      []GroupRate
+	WeightsPath     string // best weights are checkpointed here after every epoch
+	ResumePath      string // weights file to continue tuning from
+	StartEpoch      int    // epochs done before resume, used by schedule
+}
+
+// GroupRate scales learning rate of features whose name matches Pattern (path.Match syntax).
+// The first matching pattern is used.
+type GroupRate struct {
+	Pattern string
+	Scale   float64
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Epochs:          15,
+		Concurrency:     1,
+		Optimizer:       "adam",
+		LearningRate:    ml.LearningRate,
+		Momentum:        0.9,
+		Schedule:        "constant",
+		DecayRate:       0.5,
+		DecayEpochs:     5,
+		ValidationRatio: 0.2,
+	}
+}
+
+// validationSize caps validation set because it is evaluated after every epoch.
+func (config *Config) validationSize(total int) int {
+	return min(500_000, int(float64(total)*config.ValidationRatio))
+}
+
+// IFeatureGroups is implemented by evaluations with named features.
+type IFeatureGroups interface {
+	FeatureGroups() []domain.FeatureGroup
+}
+
+// learningRateScales returns learning rate scale of every feature.
+func learningRateScales(featureProvider IFeatureProvider, groupRates []GroupRate) ([]float64, error) {
+	var result = make([]float64, featureProvider.FeatureSize())
+	for i := range result {
+		result[i] = 1
+	}
+	if len(groupRates) == 0 {
+		return result, nil
+	}
+	var provider, ok = featureProvider.(IFeatureGroups)
+	if !ok {
+		return nil, fmt.Errorf("evaluation does not support feature groups")
+	}
+	var used = make([]bool, len(groupRates))
+	for _, group := range provider.FeatureGroups() {
+		for i, groupRate := range groupRates {
+			matched, err := path.Match(groupRate.Pattern, group.Name)
+			if err != nil {
+				return nil, err
+			}
+			if !matched {
+				continue
+			}
+			used[i] = true
+			for j := 0; j < group.Size; j++ {
+				result[group.Index+j] = groupRate.Scale
+			}
+			break
+		}
+	}
+	for i := range used {
+		if !used[i] {
+			return nil, fmt.Errorf("pattern %v matches no feature", groupRates[i].Pattern)
+		}
+	}
+	return result, nil
+}
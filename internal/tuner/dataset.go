@@ -13,11 +13,16 @@ import (
 func LoadDataset(
 	featureProvider func() IFeatureProvider,
 	gamesFolder string,
+	filter *dataset.Filter,
 	sigmoidScale float64,
 	searchRatio float64,
 	maxSize int,
 	concurrency int,
 ) ([]Sample, error) {
+	defer filter.LogStats()
+	if dataset.IsPackedDataset(gamesFolder) {
+		return loadPackedDataset(featureProvider, gamesFolder, filter, sigmoidScale, searchRatio, maxSize, concurrency)
+	}
 	var datasetReady = make(chan struct{})
 	var games = make(chan pgn.GameRaw, 16)
 	var results = make(chan []Sample, 16)
@@ -41,7 +46,7 @@ func LoadDataset(
 		wg.Add(1)
 		g.Go(func() error {
 			defer wg.Done()
-			return analyzeGames(ctx, games, results, featureProvider(), sigmoidScale, searchRatio)
+			return analyzeGames(ctx, games, results, featureProvider(), filter, sigmoidScale, searchRatio)
 		})
 	}
 
@@ -77,6 +82,7 @@ func analyzeGames(
 	games <-chan pgn.GameRaw,
 	samples chan<- []Sample,
 	featureProvider IFeatureProvider,
+	filter *dataset.Filter,
 	sigmoidScale float64,
 	searchRatio float64,
 ) error {
@@ -87,13 +93,10 @@ func analyzeGames(
 		}
 		var chunk []Sample
 		for i := range game.Positions {
-			var pos = &game.Positions[i]
-			var features = featureProvider.ComputeFeatures(&pos.Position)
-			var target = computeTarget(pos.Position.WhiteMove, pos.ScoreMate, pos.ScoreCentipawns, sigmoidScale, searchRatio, game.GameResult)
-			chunk = append(chunk, Sample{
-				Target:    float32(target),
-				TuneEntry: features,
-			})
+			if !filter.Accept(&game.Positions[i]) {
+				continue
+			}
+			chunk = append(chunk, makeSample(featureProvider, &game.Positions[i], game.GameResult, sigmoidScale, searchRatio))
 		}
 		if len(chunk) != 0 {
 			samples <- chunk
@@ -102,6 +105,21 @@ func analyzeGames(
 	return nil
 }
 
+func makeSample(
+	featureProvider IFeatureProvider,
+	pos *dataset.PositionInfo,
+	gameResult float64,
+	sigmoidScale float64,
+	searchRatio float64,
+) Sample {
+	var features = featureProvider.ComputeFeatures(&pos.Position)
+	var target = computeTarget(pos.Position.WhiteMove, pos.ScoreMate, pos.ScoreCentipawns, sigmoidScale, searchRatio, gameResult)
+	return Sample{
+		Target:    float32(target),
+		TuneEntry: features,
+	}
+}
+
 func computeTarget(
 	wstm bool,
 	scoreMate int,
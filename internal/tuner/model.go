@@ -14,21 +14,27 @@ type Model struct {
 	weights      ml.Matrix
 	wGradients   ml.Gradients
 	cost         ml.IModelCost
+	optimizer    ml.IOptimizer
+	trainCost    float64
+	trainCount   int
 }
 
 func NewModelHCE(
 	inputSize int,
+	optimizer ml.IOptimizer,
 ) *Model {
 	return &Model{
 		activationFn: &ml.SigmoidActivation{},
 		weights:      ml.NewMatrix(2, inputSize),
 		wGradients:   ml.NewGradients(2, inputSize),
 		cost:         &ml.MSECost{},
+		optimizer:    optimizer,
 	}
 }
 
-func (m *Model) ApplyGradients() {
-	m.wGradients.Apply(&m.weights)
+// ApplyGradients uses learning rate of every input.
+func (m *Model) ApplyGradients(learningRates []float64) {
+	m.wGradients.ApplyOptimizer(&m.weights, m.optimizer, learningRates)
 }
 
 func (m *Model) CalcCost(sample *Sample) float64 {
@@ -64,10 +70,12 @@ func (m *Model) work(sample *Sample, train bool, cost *float64) {
 	}
 	var x = mix * strongSideScale
 	var predicted = m.activationFn.Sigma(x)
+	*cost = m.cost.Cost(predicted, float64(sample.Target))
 	if !train {
-		*cost = m.cost.Cost(predicted, float64(sample.Target))
 		return
 	}
+	m.trainCost += *cost
+	m.trainCount++
 	// back propagation
 	var outputGradient = m.cost.CostPrime(predicted, float64(sample.Target)) *
 		m.activationFn.SigmaPrime(x) *
@@ -80,15 +88,27 @@ func (m *Model) work(sample *Sample, train bool, cost *float64) {
 	}
 }
 
-func (m *Model) Print() {
-	const ScaleEval = 10_000
+const ScaleEval = 10_000
+
+// IntWeights returns opening and endgame weight pairs in the evaluation scale.
+func (m *Model) IntWeights() []int {
 	var weights = m.weights.Data
 	var wInt = make([]int, len(weights))
 	for i := range wInt {
 		wInt[i] = int(math.Round(ScaleEval * weights[i]))
 	}
-	//fmt.Printf("%#v\n", weights)
-	fmt.Printf("%#v\n", wInt)
+	return wInt
+}
+
+func (m *Model) SetIntWeights(wInt []int) error {
+	var weights = m.weights.Data
+	if len(wInt) != len(weights) {
+		return fmt.Errorf("expected %v weights, got %v", len(weights), len(wInt))
+	}
+	for i := range weights {
+		weights[i] = float64(wInt[i]) / ScaleEval
+	}
+	return nil
 }
 
 func (m *Model) ThreadCopy() *Model {
@@ -97,6 +117,7 @@ func (m *Model) ThreadCopy() *Model {
 		weights:      m.weights,
 		wGradients:   ml.NewGradients(m.wGradients.Rows, m.weights.Cols),
 		cost:         m.cost,
+		optimizer:    m.optimizer,
 	}
 }
 
@@ -1,48 +1,128 @@
 package tuner
 
 import (
+	"fmt"
 	"log"
+	"math"
 	"math/rand"
 	"sync"
 	"sync/atomic"
+
+	"github.com/ChizhovVadim/CounterGo/internal/ml"
 )
 
+const BatchSize = 16384
+
 func RunTuner(
-	inputSize int,
+	featureProvider IFeatureProvider,
 	samples []Sample,
-	epochs int,
-	concurrency int,
+	config Config,
+) error {
+	var validationSize = config.validationSize(len(samples))
+	var validation = samples[:validationSize]
+	var training = samples[validationSize:]
+
+	var rnd = rand.New(rand.NewSource(0))
+	return tuneEpochs(featureProvider, validation, func(onBatch func(batch []Sample)) error {
+		shuffle(rnd, training)
+		for i := 0; i+BatchSize <= len(training); i += BatchSize {
+			onBatch(training[i : i+BatchSize])
+		}
+		return nil
+	}, config)
+}
+
+// tuneEpochs calls epoch to produce the training batches of every epoch.
+// Weights with the best validation cost are checkpointed to config.WeightsPath.
+func tuneEpochs(
+	featureProvider IFeatureProvider,
+	validation []Sample,
+	epoch func(onBatch func(batch []Sample)) error,
+	config Config,
 ) error {
 	log.Println("Train started")
 	defer log.Println("Train finished")
+	log.Printf("%+v\n", config)
 
-	var validationSize = min(500_000, len(samples)/5)
-	var validation = samples[:validationSize]
-	var training = samples[validationSize:]
+	optimizer, err := ml.NewOptimizer(config.Optimizer, config.Momentum)
+	if err != nil {
+		return err
+	}
+	schedule, err := ml.NewSchedule(config.Schedule, config.LearningRate,
+		config.DecayRate, config.DecayEpochs, config.Epochs)
+	if err != nil {
+		return err
+	}
+	scales, err := learningRateScales(featureProvider, config.GroupRates)
+	if err != nil {
+		return err
+	}
 
-	var mainModel = NewModelHCE(inputSize)
+	var mainModel = NewModelHCE(featureProvider.FeatureSize(), optimizer)
+	if config.ResumePath != "" {
+		weights, err := loadWeights(featureProvider, config.ResumePath)
+		if err != nil {
+			return err
+		}
+		err = mainModel.SetIntWeights(weights)
+		if err != nil {
+			return err
+		}
+		log.Println("Resumed", config.ResumePath, "epoch", config.StartEpoch)
+	}
 
-	const BatchSize = 16384
-	var models = make([]*Model, concurrency)
+	var models = make([]*Model, config.Concurrency)
 	models[0] = mainModel
 	for i := 1; i < len(models); i++ {
 		models[i] = mainModel.ThreadCopy()
 	}
 
-	var rnd = rand.New(rand.NewSource(0))
-	for epoch := 1; epoch <= epochs; epoch++ {
-		shuffle(rnd, training)
-		for i := 0; i+BatchSize <= len(training); i += BatchSize {
-			var batch = training[i : i+BatchSize]
+	var learningRates = make([]float64, len(scales))
+	var bestCost = math.Inf(1)
+	var bestWeights []int
+	var badEpochs int
+
+	for epochNumber := config.StartEpoch + 1; epochNumber <= config.Epochs; epochNumber++ {
+		var learningRate = schedule.LearningRate(epochNumber)
+		for i := range learningRates {
+			learningRates[i] = learningRate * scales[i]
+		}
+		var err = epoch(func(batch []Sample) {
 			trainBatch(batch, models)
-			applyGradients(models)
+			applyGradients(models, learningRates)
+		})
+		if err != nil {
+			return err
 		}
-		log.Printf("Finished Epoch %v\n", epoch)
+		var trainingCost = takeTrainingCost(models)
+		log.Printf("Finished Epoch %v\n", epochNumber)
 		validationCost := calcAverageCost(validation, models)
-		log.Printf("Current validation cost is: %f\n", validationCost)
+		log.Printf("Learning rate: %g, training cost: %f, validation cost: %f\n",
+			learningRate, trainingCost, validationCost)
+
+		if validationCost < bestCost {
+			bestCost = validationCost
+			bestWeights = mainModel.IntWeights()
+			badEpochs = 0
+			if config.WeightsPath != "" {
+				var err = saveWeights(featureProvider, bestWeights, config.WeightsPath)
+				if err != nil {
+					return err
+				}
+				log.Println("Saved checkpoint", config.WeightsPath)
+			}
+		} else {
+			badEpochs++
+			if config.Patience > 0 && badEpochs >= config.Patience {
+				log.Println("Early stopping, best validation cost", bestCost)
+				break
+			}
+		}
 	}
 
-	mainModel.Print()
+	if bestWeights != nil {
+		fmt.Printf("%#v\n", bestWeights)
+	}
 
 	return nil
 }
@@ -73,11 +153,26 @@ func trainBatch(samples []Sample, models []*Model) {
 	wg.Wait()
 }
 
-func applyGradients(models []*Model) {
+func applyGradients(models []*Model, learningRates []float64) {
 	for i := 1; i < len(models); i++ {
 		models[i].AddGradients(models[0])
 	}
-	models[0].ApplyGradients()
+	models[0].ApplyGradients(learningRates)
+}
+
+func takeTrainingCost(models []*Model) float64 {
+	var cost float64
+	var count int
+	for _, m := range models {
+		cost += m.trainCost
+		count += m.trainCount
+		m.trainCost = 0
+		m.trainCount = 0
+	}
+	if count == 0 {
+		return 0
+	}
+	return cost / float64(count)
 }
 
 func calcAverageCost(samples []Sample, models []*Model) float64 {
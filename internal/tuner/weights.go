@@ -0,0 +1,43 @@
+package tuner
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// IWeightsFile is implemented by evaluations that load weights from a parameter file.
+type IWeightsFile interface {
+	ReadWeights(r io.Reader) ([]int, error)
+	WriteWeights(w io.Writer, weights []int) error
+}
+
+func saveWeights(featureProvider IFeatureProvider, weights []int, path string) error {
+	var writer, ok = featureProvider.(IWeightsFile)
+	if !ok {
+		return fmt.Errorf("evaluation does not support weights file")
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	err = writer.WriteWeights(f, weights)
+	if err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+func loadWeights(featureProvider IFeatureProvider, path string) ([]int, error) {
+	var reader, ok = featureProvider.(IWeightsFile)
+	if !ok {
+		return nil, fmt.Errorf("evaluation does not support weights file")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return reader.ReadWeights(f)
+}
@@ -134,3 +134,20 @@ func ParseMoveSAN(pos *Position, san string) Move {
 	}
 	return MoveEmpty
 }
+
+// FormatMoveSAN returns a legal move in standard algebraic notation with check and mate suffix.
+func FormatMoveSAN(pos *Position, mv Move) string {
+	var child Position
+	if !pos.MakeMove(mv, &child) {
+		return ""
+	}
+	var san = moveToSAN(pos, pos.GenerateLegalMoves(), mv)
+	if child.IsCheck() {
+		if len(child.GenerateLegalMoves()) == 0 {
+			san += "#"
+		} else {
+			san += "+"
+		}
+	}
+	return san
+}
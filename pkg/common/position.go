@@ -89,6 +89,21 @@ func NewPositionFromFEN(fen string) (Position, error) {
 	return pos, nil
 }
 
+// NewPositionFromPieces creates position from piece types by square (Empty for empty squares).
+// white is the set of squares with white pieces.
+func NewPositionFromPieces(pieces *[64]int, white uint64, whiteMove bool,
+	castleRights, epSquare, rule50 int) (Position, error) {
+	var board [64]coloredPiece
+	for sq, piece := range pieces {
+		board[sq] = coloredPiece{Type: piece, Side: white&SquareMask[sq] != 0}
+	}
+	var pos, isLegal = createPosition(board, whiteMove, castleRights, epSquare, rule50)
+	if !isLegal {
+		return Position{}, fmt.Errorf("illegal position")
+	}
+	return pos, nil
+}
+
 func (p *Position) String() string {
 	var sb strings.Builder
 
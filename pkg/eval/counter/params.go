@@ -0,0 +1,136 @@
+package eval
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ChizhovVadim/CounterGo/internal/domain"
+)
+
+// Parameter file is a JSON object keyed by feature name.
+// Each value holds opening and endgame weight pairs of the feature:
+//
+//	"PawnValue": [5072, 5217],
+//
+// Features missing in the file keep default weights.
+
+// DefaultWeights returns a copy of the built-in weights.
+func DefaultWeights() []int {
+	var result = make([]int, len(defaultWeights))
+	copy(result, defaultWeights)
+	return result
+}
+
+func NewEvaluationServiceFromFile(path string) (*EvaluationService, error) {
+	var weights, err = LoadWeightsFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewEvaluationServiceWithWeights(weights), nil
+}
+
+func LoadWeightsFile(path string) ([]int, error) {
+	var f, err = os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadWeights(f)
+}
+
+func ReadWeights(r io.Reader) ([]int, error) {
+	var params map[string][]int
+	var err = json.NewDecoder(r).Decode(&params)
+	if err != nil {
+		return nil, err
+	}
+	var weights = DefaultWeights()
+	var found int
+	for _, f := range features {
+		var values, ok = params[f.Name]
+		if !ok {
+			continue
+		}
+		if len(values) != 2*f.Size {
+			return nil, fmt.Errorf("feature %v: expected %v weights, got %v", f.Name, 2*f.Size, len(values))
+		}
+		copy(weights[2*f.Index:], values)
+		found++
+	}
+	if found != len(params) {
+		for name := range params {
+			if findFeature(name) == nil {
+				return nil, fmt.Errorf("unknown feature %v", name)
+			}
+		}
+	}
+	return weights, nil
+}
+
+func SaveWeightsFile(path string, weights []int) error {
+	var f, err = os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	err = WriteWeights(f, weights)
+	if err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// WriteWeights writes features in registration order.
+func WriteWeights(w io.Writer, weights []int) error {
+	if len(weights) != 2*featureSize {
+		return fmt.Errorf("expected %v weights, got %v", 2*featureSize, len(weights))
+	}
+	var bw = bufio.NewWriter(w)
+	bw.WriteString("{\n")
+	for i, f := range features {
+		name, err := json.Marshal(f.Name)
+		if err != nil {
+			return err
+		}
+		values, err := json.Marshal(weights[2*f.Index : 2*(f.Index+f.Size)])
+		if err != nil {
+			return err
+		}
+		var sep = ","
+		if i == len(features)-1 {
+			sep = ""
+		}
+		fmt.Fprintf(bw, "\t%s: %s%s\n", name, values, sep)
+	}
+	bw.WriteString("}\n")
+	return bw.Flush()
+}
+
+// WriteWeights, ReadWeights and FeatureGroups are used by the tuner.
+func (e *EvaluationService) WriteWeights(w io.Writer, weights []int) error {
+	return WriteWeights(w, weights)
+}
+
+func findFeature(name string) *featureInfo {
+	for i := range features {
+		if features[i].Name == name {
+			return &features[i]
+		}
+	}
+	return nil
+}
+
+func (e *EvaluationService) ReadWeights(r io.Reader) ([]int, error) {
+	return ReadWeights(r)
+}
+
+func (e *EvaluationService) FeatureGroups() []domain.FeatureGroup {
+	var result = make([]domain.FeatureGroup, len(features))
+	for i, f := range features {
+		result[i] = domain.FeatureGroup{Name: f.Name, Index: f.Index, Size: f.Size}
+	}
+	return result
+}
@@ -0,0 +1,43 @@
+package eval
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWeightsFile(t *testing.T) {
+	var weights = DefaultWeights()
+	weights[2*fRookOpen] = 1234
+	var buf bytes.Buffer
+	var err = WriteWeights(&buf, weights)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := ReadWeights(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range weights {
+		if weights[i] != loaded[i] {
+			t.Fatalf("weight %v: expected %v, got %v", i, weights[i], loaded[i])
+		}
+	}
+
+	loaded, err = ReadWeights(strings.NewReader(`{"Tempo": [1, 2]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded[2*fTempo] != 1 || loaded[2*fTempo+1] != 2 || loaded[0] != defaultWeights[0] {
+		t.Error("partial file")
+	}
+
+	_, err = ReadWeights(strings.NewReader(`{"Unknown": [1, 2]}`))
+	if err == nil {
+		t.Error("unknown feature accepted")
+	}
+	_, err = ReadWeights(strings.NewReader(`{"Tempo": [1]}`))
+	if err == nil {
+		t.Error("bad size accepted")
+	}
+}
@@ -1,6 +1,7 @@
 package eval
 
 import (
+	"fmt"
 	"os"
 	"os/user"
 	"path/filepath"
@@ -8,36 +9,81 @@ import (
 	"sync"
 )
 
-func loadWeightsСached(loadFunc func() (*Weights, error)) func() (*Weights, error) {
+var loadDefaultNetCached = func() func() (*Net, error) {
 	var once sync.Once
-	var weights *Weights
+	var net *Net
 	var err error
-	return func() (*Weights, error) {
+	return func() (*Net, error) {
 		once.Do(func() {
-			weights, err = loadFunc()
+			net, err = loadDefaultNet()
 		})
-		return weights, err
+		return net, err
 	}
-}
-
-var loadDefaultWeightsСached = loadWeightsСached(loadDefaultWeights)
+}()
 
 // TODO return err
 func NewDefaultEvaluationService() *EvaluationService {
-	var weights, err = loadDefaultWeightsСached()
+	var net, err = loadDefaultNetCached()
+	if err != nil {
+		panic(err)
+	}
+	weights, err := net.Weights()
 	if err != nil {
 		panic(err)
 	}
 	return NewEvaluationService(weights)
 }
 
-func loadFileWeights(path string) (*Weights, error) {
-	var f, err = os.Open(path)
+var loadDefaultQuantizedWeightsCached = func() func() (*QuantizedWeights, error) {
+	var once sync.Once
+	var weights *QuantizedWeights
+	var err error
+	return func() (*QuantizedWeights, error) {
+		once.Do(func() {
+			var net *Net
+			net, err = loadDefaultNetCached()
+			if err != nil {
+				return
+			}
+			weights, err = net.QuantizedWeights()
+		})
+		return weights, err
+	}
+}()
+
+// TODO return err
+func NewDefaultQuantizedEvaluationService() *QuantizedEvaluationService {
+	var weights, err = loadDefaultQuantizedWeightsCached()
+	if err != nil {
+		panic(err)
+	}
+	return NewQuantizedEvaluationService(weights)
+}
+
+func LoadNetFile(path string) (*Net, error) {
+	var f, err = os.Open(mapPath(path))
 	if err != nil {
 		return nil, err
 	}
 	defer f.Close()
-	return LoadWeights(f)
+	net, err := ReadNet(f)
+	if err != nil {
+		return nil, fmt.Errorf("load network %v: %w", path, err)
+	}
+	return net, nil
+}
+
+func SaveNetFile(path string, net *Net) error {
+	var f, err = os.Create(mapPath(path))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	err = WriteNet(f, net)
+	if err != nil {
+		return err
+	}
+	return f.Close()
 }
 
 func mapPath(path string) string {
@@ -0,0 +1,117 @@
+package eval
+
+import (
+	"fmt"
+)
+
+// Activation of hidden layer.
+type Activation uint32
+
+const (
+	ActReLU Activation = iota
+	// ActClippedReLU is ReLU clipped to 1.
+	ActClippedReLU
+	// ActSCReLU is square of clipped ReLU.
+	ActSCReLU
+)
+
+var activationNames = [...]string{"relu", "crelu", "screlu"}
+
+func (a Activation) String() string {
+	if int(a) < len(activationNames) {
+		return activationNames[a]
+	}
+	return fmt.Sprintf("activation(%d)", uint32(a))
+}
+
+// ParseActivation accepts relu, crelu or screlu.
+func ParseActivation(name string) (Activation, error) {
+	for i, activationName := range activationNames {
+		if name == activationName {
+			return Activation(i), nil
+		}
+	}
+	return 0, fmt.Errorf("bad activation %v", name)
+}
+
+func (a Activation) valid() bool {
+	return int(a) < len(activationNames)
+}
+
+func (a Activation) apply(x float32) float32 {
+	if x <= 0 {
+		return 0
+	}
+	if a == ActReLU {
+		return x
+	}
+	if x > 1 {
+		x = 1
+	}
+	if a == ActSCReLU {
+		return x * x
+	}
+	return x
+}
+
+type DenseLayer struct {
+	Weights    []float32 // by input then by neuron
+	Biases     []float32
+	Activation Activation
+}
+
+// DenseWeights are small layers after accumulator, they are evaluated in float32.
+// Output layer has output of every bucket, its weights are by bucket then by input.
+type DenseWeights struct {
+	Activation Activation // of accumulator
+	Layers     []DenseLayer
+	Output     DenseLayer
+}
+
+// denseBuffers are inputs of dense layers and output layer.
+type denseBuffers [][]float32
+
+func (d *DenseWeights) newBuffers(inputSize int) denseBuffers {
+	var buffers = denseBuffers{make([]float32, inputSize)}
+	for i := range d.Layers {
+		buffers = append(buffers, make([]float32, len(d.Layers[i].Biases)))
+	}
+	return buffers
+}
+
+// setInput converts quantized accumulator to input of the first dense layer.
+func (d *DenseWeights) setInput(dst []float32, accumulator []int16, hiddenScale int32) {
+	var scale = 1 / float32(hiddenScale)
+	for i, x := range accumulator {
+		dst[i] = d.Activation.apply(float32(x) * scale)
+	}
+}
+
+// output computes output of bucket, inputs of the first layer are set.
+// Zero inputs are skipped, they are common after ReLU.
+func (d *DenseWeights) output(buffers denseBuffers, bucket int) float32 {
+	for i := range d.Layers {
+		var layer = &d.Layers[i]
+		var input, output = buffers[i], buffers[i+1]
+		copy(output, layer.Biases)
+		for j, x := range input {
+			if x == 0 {
+				continue
+			}
+			var weights = layer.Weights[j*len(output) : (j+1)*len(output)]
+			for k := range output {
+				output[k] += x * weights[k]
+			}
+		}
+		for k, x := range output {
+			output[k] = layer.Activation.apply(x)
+		}
+	}
+	var input = buffers[len(buffers)-1]
+	var weights = d.Output.Weights[bucket*len(input) : (bucket+1)*len(input)]
+	var result = d.Output.Biases[bucket]
+	for i, x := range input {
+		result += x * weights[i]
+	}
+	return result
+}
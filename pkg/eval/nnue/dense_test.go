@@ -0,0 +1,154 @@
+package eval
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	. "github.com/ChizhovVadim/CounterGo/pkg/common"
+)
+
+func TestDenseEvaluation(t *testing.T) {
+	for _, header := range []NetHeader{
+		{
+			Arch:        ArchAbsolute,
+			InputSize:   InputSize,
+			LayerSizes:  []int{32, 8, 1},
+			Activations: []Activation{ActSCReLU, ActReLU},
+		},
+		{
+			Arch:        ArchPerspective,
+			Layout:      DefaultKingBuckets,
+			InputSize:   DefaultKingBuckets.InputSize(),
+			LayerSizes:  []int{32, 16, 8, 4},
+			Activations: []Activation{ActClippedReLU, ActSCReLU, ActReLU},
+		},
+	} {
+		var net = randomDenseNet(t, rand.New(rand.NewSource(1)), header)
+		var newEval func() testEvaluator
+		if net.Arch == ArchPerspective {
+			var weights, err = net.QuantizedPerspectiveWeights()
+			if err != nil {
+				t.Fatal(err)
+			}
+			newEval = func() testEvaluator { return NewPerspectiveEvaluationService(weights) }
+		} else {
+			var weights, err = net.QuantizedWeights()
+			if err != nil {
+				t.Fatal(err)
+			}
+			newEval = func() testEvaluator { return NewQuantizedEvaluationService(weights) }
+		}
+
+		const tolerance = 6
+		var eval = newEval()
+		var rnd = rand.New(rand.NewSource(3))
+		for _, line := range testGames(rand.New(rand.NewSource(2)), 20, 100) {
+			eval.Init(&line[0])
+			for i := range line {
+				if i > 0 {
+					eval.MakeMove(&line[i-1], line[i].LastMove)
+				}
+				var p = &line[i]
+				var incremental = eval.EvaluateQuick(p)
+				var full = newEval().Evaluate(p)
+				if incremental != full {
+					t.Fatalf("%v: incremental %v, full %v", p.String(), incremental, full)
+				}
+				var floatEval = evaluateNetFloat(net, p)
+				if AbsDelta(full, floatEval) > tolerance {
+					t.Fatalf("%v %v: quantized %v, float %v", &net.NetHeader, p.String(), full, floatEval)
+				}
+			}
+			checkLazyUpdates(t, rnd, eval, newEval().Evaluate, line)
+		}
+	}
+}
+
+// randomDenseNet returns random network read back from network file.
+func randomDenseNet(t *testing.T, rnd *rand.Rand, header NetHeader) *Net {
+	header.OutputScale = 300
+	var net, err = NewNet(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, layer := range net.Layers {
+		var scale = 0.5
+		if i == 0 {
+			scale = 0.1
+		}
+		for _, data := range [][]float32{layer.Weights, layer.Biases} {
+			for j := range data {
+				data[j] = float32(scale * rnd.NormFloat64())
+			}
+		}
+	}
+	err = net.PinQuantScales()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	err = WriteNet(&buf, net)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := ReadNet(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = loaded.CheckTopology(&net.NetHeader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loaded
+}
+
+// evaluateNetFloat evaluates network file in float without accumulators.
+func evaluateNetFloat(n *Net, p *Position) int {
+	var sides = []bool{true}
+	if n.Arch == ArchPerspective {
+		sides = []bool{p.WhiteMove, !p.WhiteMove}
+	}
+	var hiddenSize = n.LayerSizes[0]
+	var input []float32
+	for _, side := range sides {
+		var acc = append([]float32(nil), n.Layers[0].Biases...)
+		for sq := 0; sq < 64; sq++ {
+			var piece, pieceSide = p.GetPieceTypeAndSide(sq)
+			if piece == Empty {
+				continue
+			}
+			var index = int(calculateNetInputIndex(pieceSide, piece, sq))
+			if n.Arch == ArchPerspective {
+				index = n.Layout.FeatureIndex(side, p.KingSq(side), piece, pieceSide, sq)
+			}
+			for j := range acc {
+				acc[j] += n.Layers[0].Weights[index*hiddenSize+j]
+			}
+		}
+		for _, x := range acc {
+			input = append(input, n.Activations[0].apply(x))
+		}
+	}
+	for i := 1; i < len(n.Layers); i++ {
+		var layer = &n.Layers[i]
+		var output = append([]float32(nil), layer.Biases...)
+		for j, x := range input {
+			for k := range output {
+				output[k] += x * layer.Weights[j*len(output)+k]
+			}
+		}
+		if i < len(n.Activations) {
+			for k, x := range output {
+				output[k] = n.Activations[i].apply(x)
+			}
+		}
+		input = output
+	}
+	var bucket = OutputBucket(PopCount(p.AllPieces()), len(input))
+	var output = int(input[bucket] * n.OutputScale)
+	if n.Arch == ArchAbsolute && !p.WhiteMove {
+		output = -output
+	}
+	return scaleOutput(p, output)
+}
@@ -5,10 +5,7 @@ import (
 	. "github.com/ChizhovVadim/CounterGo/pkg/common"
 )
 
-const (
-	InputSize  = 64 * 12
-	HiddenSize = 512
-)
+const InputSize = 64 * 12
 
 const (
 	Add    = 1
@@ -17,18 +14,41 @@ const (
 
 const MaxHeight = 128
 
+// EvaluationService keeps hidden layer of every ply.
+// Hidden layer is updated by moves lazily, when position is evaluated.
 type EvaluationService struct {
 	*Weights
-	updates       Updates
-	hiddenOutputs [MaxHeight][HiddenSize]float32
+	updates       [MaxHeight]Updates
+	computed      [MaxHeight]bool
+	hiddenOutputs [MaxHeight][]float32
 	currentHidden int
 }
 
+// Weights is a network with one hidden layer and output of every bucket.
 type Weights struct {
-	HiddenWeights [InputSize * HiddenSize]float32
-	HiddenBiases  [HiddenSize]float32
-	OutputWeights [HiddenSize]float32
-	OutputBias    float32
+	HiddenSize    int
+	OutputBuckets int
+	HiddenWeights []float32 // by input then by neuron
+	HiddenBiases  []float32
+	OutputWeights []float32 // by bucket then by neuron
+	OutputBiases  []float32
+}
+
+func NewWeights(hiddenSize, outputBuckets int) *Weights {
+	return &Weights{
+		HiddenSize:    hiddenSize,
+		OutputBuckets: outputBuckets,
+		HiddenWeights: make([]float32, InputSize*hiddenSize),
+		HiddenBiases:  make([]float32, hiddenSize),
+		OutputWeights: make([]float32, outputBuckets*hiddenSize),
+		OutputBiases:  make([]float32, outputBuckets),
+	}
+}
+
+// OutputBucket selects output of network by number of pieces on the board.
+func OutputBucket(pieceCount, outputBuckets int) int {
+	var piecesPerBucket = (32 + outputBuckets - 1) / outputBuckets
+	return Max(0, Min(outputBuckets-1, (pieceCount-2)/piecesPerBucket))
 }
 
 type Updates struct {
@@ -46,19 +66,29 @@ func (u *Updates) Add(index int16, coeff int8) {
 func NewEvaluationService(weights *Weights) *EvaluationService {
 	var es = &EvaluationService{}
 	es.Weights = weights
+	for i := range es.hiddenOutputs {
+		es.hiddenOutputs[i] = make([]float32, weights.HiddenSize)
+	}
 	return es
 }
 
 func (e *EvaluationService) EvaluateQuick(p *Position) int {
-	output := int(e.QuickFeed())
+	e.computeHidden()
+	var bucket = OutputBucket(PopCount(p.AllPieces()), e.OutputBuckets)
+	var output = int(e.QuickFeed(bucket))
+	if !p.WhiteMove {
+		output = -output
+	}
+	return scaleOutput(p, output)
+}
+
+// scaleOutput scales network output from side to move point of view by material and rule50.
+func scaleOutput(p *Position, output int) int {
 	const MaxEval = 15_000
 	output = Max(-MaxEval, Min(MaxEval, output))
 	var npMaterial = 4*PopCount(p.Knights|p.Bishops) + 6*PopCount(p.Rooks) + 12*PopCount(p.Queens)
 	output = output * (160 + npMaterial) / 160
 	output = output * (200 - p.Rule50) / 200
-	if !p.WhiteMove {
-		output = -output
-	}
 	return output
 }
 
@@ -78,7 +108,8 @@ func (e *EvaluationService) Init(p *Position) {
 	}
 
 	e.currentHidden = 0
-	hiddenOutputs := e.hiddenOutputs[e.currentHidden][:]
+	e.computed[e.currentHidden] = true
+	hiddenOutputs := e.hiddenOutputs[e.currentHidden]
 
 	for i := range hiddenOutputs {
 		hiddenOutputs[i] = e.HiddenBiases[i]
@@ -86,7 +117,7 @@ func (e *EvaluationService) Init(p *Position) {
 
 	for _, i := range input {
 		for j := range hiddenOutputs {
-			hiddenOutputs[j] += e.HiddenWeights[i*HiddenSize+j]
+			hiddenOutputs[j] += e.HiddenWeights[i*e.HiddenSize+j]
 		}
 	}
 }
@@ -100,31 +131,49 @@ func calculateNetInputIndex(whiteSide bool, pieceType, square int) int16 {
 }
 
 func (e *EvaluationService) MakeMove(p *Position, m Move) {
-	e.updates.Size = 0
+	e.currentHidden++
+	e.updates[e.currentHidden].fromMove(p, m)
+	e.computed[e.currentHidden] = false
+}
+
+// computeHidden applies updates of moves after the last computed hidden layer.
+func (e *EvaluationService) computeHidden() {
+	var last = e.currentHidden
+	for !e.computed[last] {
+		last--
+	}
+	for i := last + 1; i <= e.currentHidden; i++ {
+		e.updateHidden(i)
+		e.computed[i] = true
+	}
+}
+
+// fromMove collects input changes of move, null move has no changes.
+func (u *Updates) fromMove(p *Position, m Move) {
+	u.Size = 0
 
 	// MakeNullMove
 	if m == MoveEmpty {
-		e.UpdateHidden()
 		return
 	}
 
 	var from, to, movingPiece, capturedPiece, epCapSq, promotionPt, isCastling = unpackMove(p, m)
 
-	e.updates.Add(calculateNetInputIndex(p.WhiteMove, movingPiece, from), Remove)
+	u.Add(calculateNetInputIndex(p.WhiteMove, movingPiece, from), Remove)
 
 	if capturedPiece != Empty {
 		var capSq = to
 		if epCapSq != SquareNone {
 			capSq = epCapSq
 		}
-		e.updates.Add(calculateNetInputIndex(!p.WhiteMove, capturedPiece, capSq), Remove)
+		u.Add(calculateNetInputIndex(!p.WhiteMove, capturedPiece, capSq), Remove)
 	}
 
 	var pieceAfterMove = movingPiece
 	if promotionPt != Empty {
 		pieceAfterMove = promotionPt
 	}
-	e.updates.Add(calculateNetInputIndex(p.WhiteMove, pieceAfterMove, to), Add)
+	u.Add(calculateNetInputIndex(p.WhiteMove, pieceAfterMove, to), Add)
 
 	if isCastling {
 		var rookRemoveSq, rookAddSq int
@@ -146,11 +195,9 @@ func (e *EvaluationService) MakeMove(p *Position, m Move) {
 			}
 		}
 
-		e.updates.Add(calculateNetInputIndex(p.WhiteMove, Rook, rookRemoveSq), Remove)
-		e.updates.Add(calculateNetInputIndex(p.WhiteMove, Rook, rookAddSq), Add)
+		u.Add(calculateNetInputIndex(p.WhiteMove, Rook, rookRemoveSq), Remove)
+		u.Add(calculateNetInputIndex(p.WhiteMove, Rook, rookAddSq), Add)
 	}
-
-	e.UpdateHidden()
 }
 
 func (e *EvaluationService) UnmakeMove() {
@@ -187,7 +234,11 @@ func unpackMove(p *Position, m Move) (from, to, movingPiece, capturedPiece, epCa
 }
 
 func (e *EvaluationService) EvaluateProb(p *Position) float64 {
-	var centipawns = e.Evaluate(p)
+	return evaluateProb(p, e.Evaluate(p))
+}
+
+// evaluateProb converts evaluation from side to move point of view to probability of white win.
+func evaluateProb(p *Position, centipawns int) float64 {
 	if !p.WhiteMove {
 		centipawns = -centipawns
 	}
@@ -0,0 +1,60 @@
+package eval
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// benchmarkIncremental plays a fixed random game forward and back as search does,
+// every ply is made incrementally and evaluated.
+func benchmarkIncremental(b *testing.B, eval testEvaluator) {
+	var line = testGames(rand.New(rand.NewSource(1)), 1, 100)[0]
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		eval.Init(&line[0])
+		for i := 1; i < len(line); i++ {
+			eval.MakeMove(&line[i-1], line[i].LastMove)
+			eval.EvaluateQuick(&line[i])
+		}
+		for i := len(line) - 1; i > 0; i-- {
+			eval.UnmakeMove()
+			eval.EvaluateQuick(&line[i-1])
+		}
+	}
+	b.ReportMetric(float64(2*(len(line)-1)), "evals/op")
+}
+
+// loadBenchWeights reads the default network, the embed build tag embeds the same file.
+func loadBenchWeights(b *testing.B) *Weights {
+	net, err := LoadNetFile("n-30-5268.nn")
+	if err != nil {
+		b.Fatal(err)
+	}
+	weights, err := net.Weights()
+	if err != nil {
+		b.Fatal(err)
+	}
+	return weights
+}
+
+func BenchmarkEvaluation(b *testing.B) {
+	benchmarkIncremental(b, NewEvaluationService(loadBenchWeights(b)))
+}
+
+func BenchmarkQuantizedEvaluation(b *testing.B) {
+	quantizedWeights, err := QuantizeWeights(loadBenchWeights(b))
+	if err != nil {
+		b.Fatal(err)
+	}
+	benchmarkIncremental(b, NewQuantizedEvaluationService(quantizedWeights))
+}
+
+// BenchmarkPerspectiveEvaluation has random weights of king bucket network with 512 hidden neurons.
+func BenchmarkPerspectiveEvaluation(b *testing.B) {
+	var weights = randomPerspectiveWeights(rand.New(rand.NewSource(1)), DefaultKingBuckets, 512)
+	quantizedWeights, err := QuantizePerspectiveWeights(weights)
+	if err != nil {
+		b.Fatal(err)
+	}
+	benchmarkIncremental(b, NewPerspectiveEvaluationService(quantizedWeights))
+}
@@ -7,15 +7,15 @@ import (
 	"log"
 )
 
-func loadDefaultWeights() (*Weights, error) {
+func loadDefaultNet() (*Net, error) {
 	var path = mapPath("./n-30-5268.nn")
-	w, err := loadFileWeights(path)
+	w, err := LoadNetFile(path)
 	if err == nil {
 		log.Println("loaded nnue weights", "path", path)
 		return w, nil
 	}
 	path = mapPath("~/chess/n-30-5268.nn")
-	w, err = loadFileWeights(path)
+	w, err = LoadNetFile(path)
 	if err == nil {
 		log.Println("loaded nnue weights", "path", path)
 		return w, nil
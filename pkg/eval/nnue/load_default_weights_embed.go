@@ -11,14 +11,14 @@ import (
 //go:embed n-30-5268.nn
 var content embed.FS
 
-func loadDefaultWeights() (*Weights, error) {
+func loadDefaultNet() (*Net, error) {
 	const name = "n-30-5268.nn"
 	var f, err = content.Open(name)
 	if err != nil {
 		return nil, err
 	}
 	defer f.Close()
-	w, err := LoadWeights(f)
+	w, err := ReadNet(f)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,594 @@
+package eval
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"math"
+	"strings"
+)
+
+// Network file:
+//
+//	magic "CGNN", version uint32
+//	architecture, input size, layer count, layer sizes uint32
+//	activations of hidden layers uint32, version 1 files have ReLU
+//	king bucket count, mirror uint32, king buckets [64]uint8
+//	output scale float32, hidden and output quantization scales int32
+//	weights and biases of every layer float32, weights by input then by neuron
+//	crc32 of all previous bytes
+//
+// All values are little endian.
+const (
+	netMagic   = "CGNN"
+	NetVersion = 2
+)
+
+// Files of the first trainer: 768x512x1 network without checksum.
+const legacyMagic = "BZ\x02\x00"
+
+// Limits protect from huge allocations on broken files.
+const (
+	maxNetLayers    = 8
+	maxNetLayerSize = 1 << 16
+)
+
+type Architecture uint32
+
+const (
+	// ArchAbsolute network sees the board from white side and evaluates for white.
+	ArchAbsolute Architecture = iota
+	// ArchPerspective network has accumulators of both sides and evaluates for side to move.
+	ArchPerspective
+)
+
+func (a Architecture) String() string {
+	switch a {
+	case ArchAbsolute:
+		return "absolute"
+	case ArchPerspective:
+		return "perspective"
+	}
+	return fmt.Sprintf("architecture(%d)", uint32(a))
+}
+
+// NetHeader describes topology of network.
+type NetHeader struct {
+	Arch       Architecture
+	Layout     PerspectiveLayout // perspective networks only
+	InputSize  int
+	LayerSizes []int // neurons of every layer, the last layer is output
+	// Activations of every layer except output.
+	Activations []Activation
+	// OutputScale converts network output to centipawns.
+	// Trainer output is logit of win probability, so the scale is 1/sigmoidScale.
+	OutputScale float32
+	// Quantization scales, zero if they are chosen on loading.
+	HiddenScale      int32
+	QuantOutputScale int32
+}
+
+func (h *NetHeader) String() string {
+	var sizes = []string{fmt.Sprint(h.InputSize)}
+	for _, size := range h.LayerSizes {
+		sizes = append(sizes, fmt.Sprint(size))
+	}
+	var s = h.Arch.String() + " " + strings.Join(sizes, "x")
+	if h.Dense() {
+		var activations []string
+		for _, activation := range h.Activations {
+			activations = append(activations, activation.String())
+		}
+		s += " " + strings.Join(activations, ",")
+	}
+	if h.Arch == ArchPerspective && h.Layout.BucketCount > 1 {
+		s += fmt.Sprintf(" %v king buckets", h.Layout.BucketCount)
+	}
+	if h.Arch == ArchPerspective && h.Layout.Mirror {
+		s += " mirror"
+	}
+	return s
+}
+
+// LayerInputSize is the number of inputs of layer.
+// Hidden layer of perspective network is used twice, so the next layer has twice more inputs.
+func (h *NetHeader) LayerInputSize(layer int) int {
+	if layer == 0 {
+		return h.InputSize
+	}
+	if layer == 1 && h.Arch == ArchPerspective {
+		return 2 * h.LayerSizes[0]
+	}
+	return h.LayerSizes[layer-1]
+}
+
+// Dense is true if layers after accumulator are not a single output layer of ReLU accumulator.
+// Such layers are evaluated in float32.
+func (h *NetHeader) Dense() bool {
+	return len(h.LayerSizes) > 2 || len(h.Activations) != 0 && h.Activations[0] != ActReLU
+}
+
+// reluActivations is activations of network with all hidden layers ReLU.
+func reluActivations(layers int) []Activation {
+	return make([]Activation, layers-1)
+}
+
+// CheckTopology returns error if networks have different architecture or sizes.
+func (h *NetHeader) CheckTopology(expected *NetHeader) error {
+	if h.Arch != expected.Arch ||
+		h.InputSize != expected.InputSize ||
+		!equalInts(h.LayerSizes, expected.LayerSizes) ||
+		!equalActivations(h.Activations, expected.Activations) ||
+		h.Arch == ArchPerspective && h.Layout != expected.Layout {
+		return fmt.Errorf("network topology %v does not match expected %v", h, expected)
+	}
+	return nil
+}
+
+func (h *NetHeader) validate() error {
+	if h.Arch != ArchAbsolute && h.Arch != ArchPerspective {
+		return fmt.Errorf("unknown network architecture %v", h.Arch)
+	}
+	if len(h.LayerSizes) == 0 || len(h.LayerSizes) > maxNetLayers {
+		return fmt.Errorf("bad number of network layers %v", len(h.LayerSizes))
+	}
+	for _, size := range h.LayerSizes {
+		if size <= 0 || size > maxNetLayerSize {
+			return fmt.Errorf("bad network layer size %v", size)
+		}
+	}
+	if len(h.Activations) != len(h.LayerSizes)-1 {
+		return fmt.Errorf("network has %v activations for %v hidden layers", len(h.Activations), len(h.LayerSizes)-1)
+	}
+	for _, activation := range h.Activations {
+		if !activation.valid() {
+			return fmt.Errorf("unknown activation %v", activation)
+		}
+	}
+	var inputSize = InputSize
+	if h.Arch == ArchPerspective {
+		inputSize = h.Layout.InputSize()
+	}
+	if h.InputSize != inputSize {
+		return fmt.Errorf("network %v must have %v inputs", h, inputSize)
+	}
+	if h.HiddenScale < 0 || h.QuantOutputScale < 0 {
+		return fmt.Errorf("bad quantization scales %v %v", h.HiddenScale, h.QuantOutputScale)
+	}
+	return nil
+}
+
+type NetLayer struct {
+	Weights []float32 // Weights[input*neurons+neuron]
+	Biases  []float32
+}
+
+// Net is a network file loaded in memory.
+type Net struct {
+	NetHeader
+	Layers []NetLayer
+}
+
+// NewNet allocates zero weights for topology.
+func NewNet(header NetHeader) (*Net, error) {
+	var err = header.validate()
+	if err != nil {
+		return nil, err
+	}
+	var net = &Net{NetHeader: header}
+	for i, size := range header.LayerSizes {
+		net.Layers = append(net.Layers, NetLayer{
+			Weights: make([]float32, header.LayerInputSize(i)*size),
+			Biases:  make([]float32, size),
+		})
+	}
+	return net, nil
+}
+
+func (n *Net) data() [][]float32 {
+	var result [][]float32
+	for i := range n.Layers {
+		result = append(result, n.Layers[i].Weights, n.Layers[i].Biases)
+	}
+	return result
+}
+
+func WriteNet(w io.Writer, net *Net) error {
+	var err = net.validate()
+	if err != nil {
+		return err
+	}
+	var bw = bufio.NewWriter(w)
+	var crc = crc32.NewIEEE()
+	var out = io.MultiWriter(bw, crc)
+
+	var header bytes.Buffer
+	header.WriteString(netMagic)
+	var values = []uint32{NetVersion, uint32(net.Arch), uint32(net.InputSize), uint32(len(net.LayerSizes))}
+	for _, size := range net.LayerSizes {
+		values = append(values, uint32(size))
+	}
+	for _, activation := range net.Activations {
+		values = append(values, uint32(activation))
+	}
+	var mirror uint32
+	if net.Layout.Mirror {
+		mirror = 1
+	}
+	values = append(values, uint32(net.Layout.BucketCount), mirror)
+	binary.Write(&header, binary.LittleEndian, values)
+	for _, bucket := range net.Layout.KingBuckets {
+		header.WriteByte(uint8(bucket))
+	}
+	binary.Write(&header, binary.LittleEndian, math.Float32bits(net.OutputScale))
+	binary.Write(&header, binary.LittleEndian, []int32{net.HiddenScale, net.QuantOutputScale})
+	_, err = out.Write(header.Bytes())
+	if err != nil {
+		return err
+	}
+
+	for _, data := range net.data() {
+		err = binary.Write(out, binary.LittleEndian, data)
+		if err != nil {
+			return err
+		}
+	}
+	err = binary.Write(bw, binary.LittleEndian, crc.Sum32())
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// ReadNet reads network file. Legacy files of the first trainer are also accepted.
+func ReadNet(r io.Reader) (*Net, error) {
+	var br = bufio.NewReader(r)
+	var magic [4]byte
+	_, err := io.ReadFull(br, magic[:])
+	if err != nil {
+		return nil, fmt.Errorf("read network header: %w", err)
+	}
+	if string(magic[:]) == legacyMagic {
+		return readLegacyNet(br)
+	}
+	if string(magic[:]) != netMagic {
+		return nil, errors.New("not a network file")
+	}
+
+	var crc = crc32.NewIEEE()
+	crc.Write(magic[:])
+	var cr = &checksumReader{r: br, crc: crc}
+
+	version, err := cr.uint32()
+	if err != nil {
+		return nil, err
+	}
+	if version != 1 && version != NetVersion {
+		return nil, fmt.Errorf("unsupported network file version %v, expected %v", version, NetVersion)
+	}
+	var header NetHeader
+	var values [3]uint32
+	for i := range values {
+		values[i], err = cr.uint32()
+		if err != nil {
+			return nil, err
+		}
+	}
+	header.Arch = Architecture(values[0])
+	header.InputSize = int(values[1])
+	var layers = int(values[2])
+	if layers > maxNetLayers {
+		return nil, fmt.Errorf("bad number of network layers %v", layers)
+	}
+	for i := 0; i < layers; i++ {
+		size, err := cr.uint32()
+		if err != nil {
+			return nil, err
+		}
+		header.LayerSizes = append(header.LayerSizes, int(size))
+	}
+	if layers == 0 {
+		return nil, fmt.Errorf("bad number of network layers %v", layers)
+	}
+	header.Activations = reluActivations(layers)
+	if version != 1 {
+		for i := range header.Activations {
+			activation, err := cr.uint32()
+			if err != nil {
+				return nil, err
+			}
+			header.Activations[i] = Activation(activation)
+		}
+	}
+	bucketCount, err := cr.uint32()
+	if err != nil {
+		return nil, err
+	}
+	mirror, err := cr.uint32()
+	if err != nil {
+		return nil, err
+	}
+	var buckets [64]uint8
+	err = cr.read(buckets[:])
+	if err != nil {
+		return nil, err
+	}
+	if header.Arch == ArchPerspective {
+		var kingBuckets = make([]int, len(buckets))
+		for i, bucket := range buckets {
+			kingBuckets[i] = int(bucket)
+		}
+		header.Layout = NewPerspectiveLayout(kingBuckets, mirror != 0)
+		if header.Layout.BucketCount != int(bucketCount) {
+			return nil, fmt.Errorf("king buckets do not match bucket count %v", bucketCount)
+		}
+	}
+	outputScale, err := cr.uint32()
+	if err != nil {
+		return nil, err
+	}
+	header.OutputScale = math.Float32frombits(outputScale)
+	var scales [2]int32
+	err = cr.read(&scales)
+	if err != nil {
+		return nil, err
+	}
+	header.HiddenScale, header.QuantOutputScale = scales[0], scales[1]
+
+	net, err := NewNet(header)
+	if err != nil {
+		return nil, err
+	}
+	for _, data := range net.data() {
+		err = cr.read(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var expected = crc.Sum32()
+	var checksum uint32
+	err = binary.Read(br, binary.LittleEndian, &checksum)
+	if err != nil {
+		return nil, fmt.Errorf("read network checksum: %w", err)
+	}
+	if checksum != expected {
+		return nil, errors.New("network file checksum mismatch")
+	}
+	return net, nil
+}
+
+// ReadRawNet reads weights without header in the order of network file, e.g. old trainer files.
+func ReadRawNet(r io.Reader, header NetHeader) (*Net, error) {
+	var net, err = NewNet(header)
+	if err != nil {
+		return nil, err
+	}
+	var br = bufio.NewReader(r)
+	for _, data := range net.data() {
+		err = binary.Read(br, binary.LittleEndian, data)
+		if err != nil {
+			return nil, fmt.Errorf("read network weights %v: %w", header.String(), err)
+		}
+	}
+	if _, err = br.ReadByte(); err != io.EOF {
+		return nil, fmt.Errorf("file is larger than network %v", header.String())
+	}
+	return net, nil
+}
+
+func readLegacyNet(r io.Reader) (*Net, error) {
+	// version, inputs, outputs, hidden layers
+	var values [4]uint32
+	var err = binary.Read(r, binary.LittleEndian, &values)
+	if err != nil {
+		return nil, fmt.Errorf("read network header: %w", err)
+	}
+	var layers = int(values[3])
+	if layers > maxNetLayers {
+		return nil, fmt.Errorf("bad number of network layers %v", layers)
+	}
+	var header = NetHeader{
+		Arch:        ArchAbsolute,
+		InputSize:   int(values[1]),
+		OutputScale: 1,
+	}
+	for i := 0; i < layers; i++ {
+		var size uint32
+		err = binary.Read(r, binary.LittleEndian, &size)
+		if err != nil {
+			return nil, fmt.Errorf("read network header: %w", err)
+		}
+		header.LayerSizes = append(header.LayerSizes, int(size))
+	}
+	header.LayerSizes = append(header.LayerSizes, int(values[2]))
+	header.Activations = reluActivations(len(header.LayerSizes))
+	net, err := NewNet(header)
+	if err != nil {
+		return nil, err
+	}
+	for _, data := range net.data() {
+		err = binary.Read(r, binary.LittleEndian, data)
+		if err != nil {
+			return nil, fmt.Errorf("read network weights: %w", err)
+		}
+	}
+	return net, nil
+}
+
+// checksumReader computes checksum of all read bytes.
+type checksumReader struct {
+	r   io.Reader
+	crc hash.Hash32
+}
+
+func (r *checksumReader) read(data interface{}) error {
+	var err = binary.Read(io.TeeReader(r.r, r.crc), binary.LittleEndian, data)
+	if err != nil {
+		return fmt.Errorf("read network file: %w", err)
+	}
+	return nil
+}
+
+func (r *checksumReader) uint32() (uint32, error) {
+	var v uint32
+	var err = r.read(&v)
+	return v, err
+}
+
+// Weights converts network to the engine network with OutputScale applied.
+// Hidden layer may have any size, every output neuron is output bucket.
+func (n *Net) Weights() (*Weights, error) {
+	if n.Arch != ArchAbsolute || n.Dense() {
+		return nil, fmt.Errorf("network topology %v does not match absolute network with one ReLU hidden layer", &n.NetHeader)
+	}
+	var w = NewWeights(n.LayerSizes[0], n.LayerSizes[1])
+	copy(w.HiddenWeights, n.Layers[0].Weights)
+	copy(w.HiddenBiases, n.Layers[0].Biases)
+	n.outputLayer(w.OutputWeights, w.OutputBiases)
+	return w, nil
+}
+
+// PerspectiveWeights converts network to the engine network with OutputScale applied.
+func (n *Net) PerspectiveWeights() (*PerspectiveWeights, error) {
+	if n.Arch != ArchPerspective || n.Dense() {
+		return nil, fmt.Errorf("network topology %v does not match perspective network with one ReLU hidden layer", &n.NetHeader)
+	}
+	var w = NewPerspectiveWeights(n.Layout, n.LayerSizes[0], n.LayerSizes[1])
+	copy(w.HiddenWeights, n.Layers[0].Weights)
+	copy(w.HiddenBiases, n.Layers[0].Biases)
+	n.outputLayer(w.OutputWeights, w.OutputBiases)
+	return w, nil
+}
+
+// outputLayer reorders weights of the last layer by bucket and scales them to centipawns.
+func (n *Net) outputLayer(weights, biases []float32) {
+	var layer = &n.Layers[len(n.Layers)-1]
+	var buckets = len(layer.Biases)
+	var inputs = len(layer.Weights) / buckets
+	for bucket := range layer.Biases {
+		for i := 0; i < inputs; i++ {
+			weights[bucket*inputs+i] = layer.Weights[i*buckets+bucket] * n.OutputScale
+		}
+		biases[bucket] = layer.Biases[bucket] * n.OutputScale
+	}
+}
+
+// QuantizedWeights quantizes network with scales of file if they are set.
+// Layers after accumulator of dense network are not quantized.
+func (n *Net) QuantizedWeights() (*QuantizedWeights, error) {
+	if n.Arch == ArchAbsolute && n.Dense() {
+		return n.quantizeDense()
+	}
+	var w, err = n.Weights()
+	if err != nil {
+		return nil, err
+	}
+	return quantize(w.HiddenSize, w.OutputBuckets, w.HiddenWeights, w.HiddenBiases, w.OutputWeights, w.OutputBiases, n.quantScales())
+}
+
+// QuantizedPerspectiveWeights quantizes network with scales of file if they are set.
+func (n *Net) QuantizedPerspectiveWeights() (*QuantizedPerspectiveWeights, error) {
+	if n.Arch == ArchPerspective && n.Dense() {
+		var q, err = n.quantizeDense()
+		if err != nil {
+			return nil, err
+		}
+		return &QuantizedPerspectiveWeights{
+			Layout:           n.Layout,
+			QuantizedWeights: q,
+		}, nil
+	}
+	var w, err = n.PerspectiveWeights()
+	if err != nil {
+		return nil, err
+	}
+	return quantizePerspective(w, n.quantScales())
+}
+
+// PinQuantScales stores the largest safe quantization scales in the header.
+func (n *Net) PinQuantScales() error {
+	var q *QuantizedWeights
+	switch n.Arch {
+	case ArchPerspective:
+		var w, err = n.QuantizedPerspectiveWeights()
+		if err != nil {
+			return err
+		}
+		q = w.QuantizedWeights
+	default:
+		var w, err = n.QuantizedWeights()
+		if err != nil {
+			return err
+		}
+		q = w
+	}
+	n.HiddenScale, n.QuantOutputScale = q.HiddenScale, q.OutputScale
+	return nil
+}
+
+// quantizeDense quantizes hidden layer and converts the next layers to dense layers with OutputScale applied.
+func (n *Net) quantizeDense() (*QuantizedWeights, error) {
+	if n.QuantOutputScale != 0 {
+		return nil, fmt.Errorf("network %v has no quantized output layer", &n.NetHeader)
+	}
+	var hiddenSize = n.LayerSizes[0]
+	var hidden, err = quantizeHidden(hiddenSize, n.Layers[0].Weights, n.Layers[0].Biases, n.HiddenScale)
+	if err != nil {
+		return nil, err
+	}
+	var dense = &DenseWeights{Activation: n.Activations[0]}
+	for i := 1; i < len(n.Layers)-1; i++ {
+		dense.Layers = append(dense.Layers, DenseLayer{
+			Weights:    append([]float32(nil), n.Layers[i].Weights...),
+			Biases:     append([]float32(nil), n.Layers[i].Biases...),
+			Activation: n.Activations[i],
+		})
+	}
+	var output = &n.Layers[len(n.Layers)-1]
+	dense.Output = DenseLayer{
+		Weights: make([]float32, len(output.Weights)),
+		Biases:  make([]float32, len(output.Biases)),
+	}
+	n.outputLayer(dense.Output.Weights, dense.Output.Biases)
+	return &QuantizedWeights{
+		HiddenSize:    hiddenSize,
+		OutputBuckets: len(output.Biases),
+		HiddenWeights: hidden.weights,
+		HiddenBiases:  hidden.biases,
+		HiddenScale:   hidden.scale,
+		Dense:         dense,
+	}, nil
+}
+
+func (n *Net) quantScales() quantScales {
+	return quantScales{hidden: n.HiddenScale, output: n.QuantOutputScale}
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func equalActivations(a, b []Activation) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,103 @@
+package eval
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNetFile(t *testing.T) {
+	var rnd = rand.New(rand.NewSource(1))
+	net, err := NewNet(NetHeader{
+		Arch:        ArchPerspective,
+		Layout:      DefaultKingBuckets,
+		InputSize:   DefaultKingBuckets.InputSize(),
+		LayerSizes:  []int{32, 1},
+		Activations: []Activation{ActReLU},
+		OutputScale: 250,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, data := range net.data() {
+		for i := range data {
+			data[i] = float32(rnd.NormFloat64() * 0.1)
+		}
+	}
+	err = net.PinQuantScales()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	err = WriteNet(&buf, net)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var file = buf.Bytes()
+	loaded, err := ReadNet(bytes.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = loaded.CheckTopology(&net.NetHeader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.OutputScale != net.OutputScale ||
+		loaded.HiddenScale != net.HiddenScale ||
+		loaded.QuantOutputScale != net.QuantOutputScale {
+		t.Fatalf("header %+v, expected %+v", loaded.NetHeader, net.NetHeader)
+	}
+	var expected, actual = net.data(), loaded.data()
+	for i := range expected {
+		for j := range expected[i] {
+			if expected[i][j] != actual[i][j] {
+				t.Fatalf("weight %v %v: %v, expected %v", i, j, actual[i][j], expected[i][j])
+			}
+		}
+	}
+
+	var corrupted = append([]byte(nil), file...)
+	corrupted[len(corrupted)/2] ^= 1
+	_, err = ReadNet(bytes.NewReader(corrupted))
+	if err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Fatalf("corrupted file: %v", err)
+	}
+	_, err = ReadNet(bytes.NewReader(file[:len(file)-10]))
+	if err == nil {
+		t.Fatal("truncated file is loaded")
+	}
+	_, err = loaded.Weights()
+	if err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Fatalf("perspective network as absolute: %v", err)
+	}
+}
+
+func TestLegacyNetFile(t *testing.T) {
+	net, err := LoadNetFile("n-30-5268.nn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if net.Arch != ArchAbsolute || net.InputSize != InputSize ||
+		!equalInts(net.LayerSizes, []int{512, 1}) || net.OutputScale != 1 {
+		t.Fatalf("legacy network %v", &net.NetHeader)
+	}
+	var buf bytes.Buffer
+	err = WriteNet(&buf, net)
+	if err != nil {
+		t.Fatal(err)
+	}
+	converted, err := LoadWeights(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	weights, err := net.Weights()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(converted, weights) {
+		t.Fatal("converted network differs")
+	}
+}
@@ -5,24 +5,26 @@ package eval
 
 const AvxInstructions = false
 
-func (e *EvaluationService) QuickFeed() float32 {
+func (e *EvaluationService) QuickFeed(bucket int) float32 {
+	var outputWeights = e.OutputWeights[bucket*e.HiddenSize:]
 	var output float32
-	for i, x := range e.hiddenOutputs[e.currentHidden][:] {
+	for i, x := range e.hiddenOutputs[e.currentHidden] {
 		if x > 0 {
-			output += x * e.OutputWeights[i]
+			output += x * outputWeights[i]
 		}
 	}
-	return output + e.OutputBias
+	return output + e.OutputBiases[bucket]
 }
 
-func (e *EvaluationService) UpdateHidden() {
-	e.currentHidden++
-	hiddenOutputs := e.hiddenOutputs[e.currentHidden][:]
-	copy(hiddenOutputs, e.hiddenOutputs[e.currentHidden-1][:])
+// updateHidden computes hidden layer of ply from previous ply.
+func (e *EvaluationService) updateHidden(ply int) {
+	var updates = &e.updates[ply]
+	hiddenOutputs := e.hiddenOutputs[ply]
+	copy(hiddenOutputs, e.hiddenOutputs[ply-1])
 
-	for i := 0; i < e.updates.Size; i++ {
-		var index = int(e.updates.Indices[i]) * HiddenSize
-		if e.updates.Coeffs[i] == Add {
+	for i := 0; i < updates.Size; i++ {
+		var index = int(updates.Indices[i]) * e.HiddenSize
+		if updates.Coeffs[i] == Add {
 			for j := range hiddenOutputs {
 				hiddenOutputs[j] += e.HiddenWeights[index+j]
 			}
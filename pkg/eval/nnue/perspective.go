@@ -0,0 +1,325 @@
+package eval
+
+import (
+	. "github.com/ChizhovVadim/CounterGo/pkg/common"
+)
+
+// PerspectiveLayout describes inputs of perspective networks.
+// Each side sees the board from its own side: own pieces first, squares flipped for black.
+// With Mirror files are mirrored when own king is on files e-h.
+// Inputs are repeated for every bucket of own king square.
+type PerspectiveLayout struct {
+	KingBuckets [64]int
+	BucketCount int
+	Mirror      bool
+}
+
+// NoKingBuckets has single bucket without mirroring.
+var NoKingBuckets = PerspectiveLayout{BucketCount: 1}
+
+// DefaultKingBuckets separates king on the first rank by wing, on the second rank and in front of pawns.
+var DefaultKingBuckets = NewPerspectiveLayout([]int{
+	0, 0, 1, 1, 1, 1, 0, 0,
+	2, 2, 2, 2, 2, 2, 2, 2,
+	3, 3, 3, 3, 3, 3, 3, 3,
+	3, 3, 3, 3, 3, 3, 3, 3,
+	3, 3, 3, 3, 3, 3, 3, 3,
+	3, 3, 3, 3, 3, 3, 3, 3,
+	3, 3, 3, 3, 3, 3, 3, 3,
+	3, 3, 3, 3, 3, 3, 3, 3,
+}, true)
+
+// NewPerspectiveLayout takes buckets by king square from a1 to h8.
+// Buckets of files e-h are ignored with mirror.
+func NewPerspectiveLayout(kingBuckets []int, mirror bool) PerspectiveLayout {
+	var layout = PerspectiveLayout{Mirror: mirror}
+	for sq := range layout.KingBuckets {
+		var bucket = kingBuckets[sq]
+		if mirror && File(sq) >= 4 {
+			bucket = kingBuckets[sq^7]
+		}
+		layout.KingBuckets[sq] = bucket
+		layout.BucketCount = Max(layout.BucketCount, bucket+1)
+	}
+	return layout
+}
+
+func (l *PerspectiveLayout) InputSize() int {
+	return 64 * 12 * l.BucketCount
+}
+
+// perspectiveView is orientation of the board for one side.
+type perspectiveView struct {
+	flip   int // xor mask of squares
+	offset int // first input of king bucket
+}
+
+func (l *PerspectiveLayout) view(side bool, kingSq int) perspectiveView {
+	var flip int
+	if !side {
+		flip = 56
+	}
+	if l.Mirror && File(kingSq^flip) >= 4 {
+		flip ^= 7
+	}
+	return perspectiveView{
+		flip:   flip,
+		offset: 64 * 12 * l.KingBuckets[kingSq^flip],
+	}
+}
+
+// cacheIndex is the index of view in refresh table of side.
+func (v perspectiveView) cacheIndex() int {
+	var index = v.offset / (64 * 12) * 2
+	if v.flip&7 != 0 {
+		index++
+	}
+	return index
+}
+
+// inputIndex converts input of absolute network to input of side.
+func (v perspectiveView) inputIndex(side bool, absoluteIndex int) int {
+	var piece12 = absoluteIndex >> 6
+	var sq = absoluteIndex & 63
+	if !side {
+		if piece12 < 6 {
+			piece12 += 6
+		} else {
+			piece12 -= 6
+		}
+	}
+	return v.offset + piece12<<6 + (sq ^ v.flip)
+}
+
+// FeatureIndex is the input of piece for side with king on kingSq.
+func (l *PerspectiveLayout) FeatureIndex(side bool, kingSq int, pieceType int, pieceSide bool, sq int) int {
+	return l.view(side, kingSq).inputIndex(side, int(calculateNetInputIndex(pieceSide, pieceType, sq)))
+}
+
+// PerspectiveWeights is a network with hidden layer shared by both sides.
+// Output weights of every bucket are for side to move then for opponent, output is from side to move point of view.
+type PerspectiveWeights struct {
+	Layout        PerspectiveLayout
+	HiddenSize    int
+	OutputBuckets int
+	HiddenWeights []float32
+	HiddenBiases  []float32
+	OutputWeights []float32
+	OutputBiases  []float32
+}
+
+func NewPerspectiveWeights(layout PerspectiveLayout, hiddenSize, outputBuckets int) *PerspectiveWeights {
+	return &PerspectiveWeights{
+		Layout:        layout,
+		HiddenSize:    hiddenSize,
+		OutputBuckets: outputBuckets,
+		HiddenWeights: make([]float32, layout.InputSize()*hiddenSize),
+		HiddenBiases:  make([]float32, hiddenSize),
+		OutputWeights: make([]float32, outputBuckets*2*hiddenSize),
+		OutputBiases:  make([]float32, outputBuckets),
+	}
+}
+
+type QuantizedPerspectiveWeights struct {
+	Layout PerspectiveLayout
+	*QuantizedWeights
+}
+
+func QuantizePerspectiveWeights(w *PerspectiveWeights) (*QuantizedPerspectiveWeights, error) {
+	return quantizePerspective(w, quantScales{})
+}
+
+func quantizePerspective(w *PerspectiveWeights, scales quantScales) (*QuantizedPerspectiveWeights, error) {
+	var q, err = quantize(w.HiddenSize, w.OutputBuckets, w.HiddenWeights, w.HiddenBiases, w.OutputWeights, w.OutputBiases, scales)
+	if err != nil {
+		return nil, err
+	}
+	return &QuantizedPerspectiveWeights{
+		Layout:           w.Layout,
+		QuantizedWeights: q,
+	}, nil
+}
+
+// perspectiveAccumulator is accumulator of ply, values are computed lazily.
+// Side with refresh needs accumulator from scratch because its king changed view.
+type perspectiveAccumulator struct {
+	values   [COLOUR_NB][]int16
+	views    [COLOUR_NB]perspectiveView
+	computed [COLOUR_NB]bool
+	refresh  [COLOUR_NB]bool
+	updates  Updates
+}
+
+// refreshEntry is the last accumulator of view and the pieces it was computed for.
+// Refresh applies only difference of pieces to it.
+type refreshEntry struct {
+	values []int16
+	pieces [COLOUR_NB][King + 1]uint64
+}
+
+// PerspectiveEvaluationService keeps accumulator of every side.
+// Accumulators are updated by moves lazily, when position is evaluated.
+// Accumulator of side is refreshed when its king changes bucket or mirror.
+type PerspectiveEvaluationService struct {
+	*QuantizedPerspectiveWeights
+	accumulators [MaxHeight]perspectiveAccumulator
+	refreshTable [COLOUR_NB][]refreshEntry // by view of side
+	current      int
+	dense        denseBuffers
+}
+
+func NewPerspectiveEvaluationService(weights *QuantizedPerspectiveWeights) *PerspectiveEvaluationService {
+	var es = &PerspectiveEvaluationService{
+		QuantizedPerspectiveWeights: weights,
+	}
+	for i := range es.accumulators {
+		for side := range es.accumulators[i].values {
+			es.accumulators[i].values[side] = make([]int16, weights.HiddenSize)
+		}
+	}
+	if weights.Dense != nil {
+		es.dense = weights.Dense.newBuffers(2 * weights.HiddenSize)
+	}
+	for side := range es.refreshTable {
+		es.refreshTable[side] = make([]refreshEntry, 2*weights.Layout.BucketCount)
+		for i := range es.refreshTable[side] {
+			var entry = &es.refreshTable[side][i]
+			entry.values = make([]int16, weights.HiddenSize)
+			copy(entry.values, weights.HiddenBiases)
+		}
+	}
+	return es
+}
+
+func (e *PerspectiveEvaluationService) EvaluateQuick(p *Position) int {
+	e.computeAccumulator(p, true)
+	e.computeAccumulator(p, false)
+	var acc = &e.accumulators[e.current]
+	var us, them = colourIndex(p.WhiteMove), colourIndex(!p.WhiteMove)
+	var bucket, outputWeights = e.outputBucket(p)
+	if e.Dense != nil {
+		e.Dense.setInput(e.dense[0][:e.HiddenSize], acc.values[us], e.HiddenScale)
+		e.Dense.setInput(e.dense[0][e.HiddenSize:], acc.values[them], e.HiddenScale)
+		return scaleOutput(p, int(e.Dense.output(e.dense, bucket)))
+	}
+	var dot = dotRelu16(acc.values[us], outputWeights[:e.HiddenSize]) +
+		dotRelu16(acc.values[them], outputWeights[e.HiddenSize:])
+	return scaleOutput(p, e.output(dot, bucket))
+}
+
+func (e *PerspectiveEvaluationService) Evaluate(p *Position) int {
+	e.Init(p)
+	return e.EvaluateQuick(p)
+}
+
+func (e *PerspectiveEvaluationService) Init(p *Position) {
+	e.current = 0
+	var acc = &e.accumulators[e.current]
+	for _, side := range [...]bool{true, false} {
+		var index = colourIndex(side)
+		acc.views[index] = e.Layout.view(side, p.KingSq(side))
+		acc.computed[index] = false
+		acc.refresh[index] = true
+	}
+}
+
+func (e *PerspectiveEvaluationService) MakeMove(p *Position, m Move) {
+	e.current++
+	var prev = &e.accumulators[e.current-1]
+	var acc = &e.accumulators[e.current]
+	acc.updates.fromMove(p, m)
+	for _, side := range [...]bool{true, false} {
+		var index = colourIndex(side)
+		acc.views[index] = prev.views[index]
+		acc.computed[index] = false
+		acc.refresh[index] = false
+		if m != MoveEmpty && m.MovingPiece() == King && side == p.WhiteMove {
+			var view = e.Layout.view(side, m.To())
+			if view != prev.views[index] {
+				acc.views[index] = view
+				acc.refresh[index] = true
+			}
+		}
+	}
+}
+
+// computeAccumulator applies updates of moves after the last computed accumulator of side.
+// If king of side changed view since then, accumulator is refreshed from position.
+func (e *PerspectiveEvaluationService) computeAccumulator(p *Position, side bool) {
+	var index = colourIndex(side)
+	var last = e.current
+	for !e.accumulators[last].computed[index] && !e.accumulators[last].refresh[index] {
+		last--
+	}
+	if !e.accumulators[last].computed[index] {
+		e.refreshAccumulator(p, side)
+		return
+	}
+	for ply := last + 1; ply <= e.current; ply++ {
+		var prev = &e.accumulators[ply-1]
+		var acc = &e.accumulators[ply]
+		e.applyUpdates(acc.values[index], prev.values[index], &acc.updates, side, acc.views[index])
+		acc.computed[index] = true
+	}
+}
+
+// refreshAccumulator computes accumulator of side from refresh entry of its view.
+func (e *PerspectiveEvaluationService) refreshAccumulator(p *Position, side bool) {
+	var index = colourIndex(side)
+	var acc = &e.accumulators[e.current]
+	var view = acc.views[index]
+	var entry = &e.refreshTable[index][view.cacheIndex()]
+	for colour := range entry.pieces {
+		var pieceSide = colour == SideWhite
+		var colourPieces = p.PiecesByColor(pieceSide)
+		for pieceType := Pawn; pieceType <= King; pieceType++ {
+			var pieces = piecesByType(p, pieceType) & colourPieces
+			var removed = entry.pieces[colour][pieceType] &^ pieces
+			var added = pieces &^ entry.pieces[colour][pieceType]
+			for x := removed; x != 0; x &= x - 1 {
+				var input = view.inputIndex(side, int(calculateNetInputIndex(pieceSide, pieceType, FirstOne(x))))
+				subWeights16(entry.values, entry.values, e.hiddenRow(input))
+			}
+			for x := added; x != 0; x &= x - 1 {
+				var input = view.inputIndex(side, int(calculateNetInputIndex(pieceSide, pieceType, FirstOne(x))))
+				addWeights16(entry.values, entry.values, e.hiddenRow(input))
+			}
+			entry.pieces[colour][pieceType] = pieces
+		}
+	}
+	copy(acc.values[index], entry.values)
+	acc.computed[index] = true
+}
+
+func (e *PerspectiveEvaluationService) UnmakeMove() {
+	e.current--
+}
+
+func (e *PerspectiveEvaluationService) EvaluateProb(p *Position) float64 {
+	return evaluateProb(p, e.Evaluate(p))
+}
+
+func piecesByType(p *Position, pieceType int) uint64 {
+	switch pieceType {
+	case Pawn:
+		return p.Pawns
+	case Knight:
+		return p.Knights
+	case Bishop:
+		return p.Bishops
+	case Rook:
+		return p.Rooks
+	case Queen:
+		return p.Queens
+	case King:
+		return p.Kings
+	}
+	return 0
+}
+
+func colourIndex(side bool) int {
+	if side {
+		return SideWhite
+	}
+	return SideBlack
+}
@@ -0,0 +1,88 @@
+package eval
+
+import (
+	"math/rand"
+	"testing"
+
+	. "github.com/ChizhovVadim/CounterGo/pkg/common"
+)
+
+func TestPerspectiveEvaluation(t *testing.T) {
+	for _, layout := range []PerspectiveLayout{NoKingBuckets, DefaultKingBuckets} {
+		var weights = randomPerspectiveWeights(rand.New(rand.NewSource(1)), layout, 64)
+		quantizedWeights, err := QuantizePerspectiveWeights(weights)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var eval = NewPerspectiveEvaluationService(quantizedWeights)
+
+		const tolerance = 4
+		var rnd = rand.New(rand.NewSource(3))
+		for _, line := range testGames(rand.New(rand.NewSource(2)), 50, 100) {
+			eval.Init(&line[0])
+			for i := range line {
+				if i > 0 {
+					eval.MakeMove(&line[i-1], line[i].LastMove)
+				}
+				var p = &line[i]
+				var incremental = eval.EvaluateQuick(p)
+				var full = NewPerspectiveEvaluationService(quantizedWeights).Evaluate(p)
+				if incremental != full {
+					t.Fatalf("%v: incremental %v, full %v", p.String(), incremental, full)
+				}
+				var mirror = MirrorPosition(p)
+				var mirrorEval = NewPerspectiveEvaluationService(quantizedWeights).Evaluate(&mirror)
+				if mirrorEval != full {
+					t.Fatalf("%v: eval %v, mirror eval %v", p.String(), full, mirrorEval)
+				}
+				var floatEval = evaluatePerspectiveFloat(weights, p)
+				if AbsDelta(full, floatEval) > tolerance {
+					t.Fatalf("%v: quantized %v, float %v", p.String(), full, floatEval)
+				}
+			}
+			checkLazyUpdates(t, rnd, eval, NewPerspectiveEvaluationService(quantizedWeights).Evaluate, line)
+		}
+	}
+}
+
+func randomPerspectiveWeights(rnd *rand.Rand, layout PerspectiveLayout, hiddenSize int) *PerspectiveWeights {
+	var w = NewPerspectiveWeights(layout, hiddenSize, 4)
+	for _, data := range [][]float32{w.HiddenWeights, w.HiddenBiases} {
+		for i := range data {
+			data[i] = float32(rnd.Float64() - 0.5)
+		}
+	}
+	for i := range w.OutputWeights {
+		w.OutputWeights[i] = float32(100 * (rnd.Float64() - 0.5))
+	}
+	for i := range w.OutputBiases {
+		w.OutputBiases[i] = float32(10 * i)
+	}
+	return w
+}
+
+func evaluatePerspectiveFloat(w *PerspectiveWeights, p *Position) int {
+	var bucket = OutputBucket(PopCount(p.AllPieces()), w.OutputBuckets)
+	var outputWeights = w.OutputWeights[bucket*2*w.HiddenSize:]
+	var output = w.OutputBiases[bucket]
+	for i, side := range [...]bool{p.WhiteMove, !p.WhiteMove} {
+		var acc = make([]float32, w.HiddenSize)
+		copy(acc, w.HiddenBiases)
+		for sq := 0; sq < 64; sq++ {
+			var piece, pieceSide = p.GetPieceTypeAndSide(sq)
+			if piece == Empty {
+				continue
+			}
+			var input = w.Layout.FeatureIndex(side, p.KingSq(side), piece, pieceSide, sq)
+			for j := range acc {
+				acc[j] += w.HiddenWeights[input*w.HiddenSize+j]
+			}
+		}
+		for j, x := range acc {
+			if x > 0 {
+				output += x * outputWeights[i*w.HiddenSize+j]
+			}
+		}
+	}
+	return scaleOutput(p, int(output))
+}
@@ -0,0 +1,294 @@
+package eval
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	. "github.com/ChizhovVadim/CounterGo/pkg/common"
+)
+
+// QuantizedWeights is a network with int16 hidden layer and int32 output.
+// Hidden values are scaled by HiddenScale, output weights by OutputScale.
+// Output weights are by bucket, of perspective networks for side to move then for opponent.
+// Network with Dense layers has no quantized output layer.
+type QuantizedWeights struct {
+	HiddenSize    int
+	OutputBuckets int
+	HiddenWeights []int16
+	HiddenBiases  []int16
+	OutputWeights []int16
+	OutputBiases  []int32
+	HiddenScale   int32
+	OutputScale   int32
+	Dense         *DenseWeights
+}
+
+// maxActiveInputs is the number of pieces on the board.
+const maxActiveInputs = 32
+
+func QuantizeWeights(w *Weights) (*QuantizedWeights, error) {
+	return quantize(w.HiddenSize, w.OutputBuckets, w.HiddenWeights, w.HiddenBiases, w.OutputWeights, w.OutputBiases, quantScales{})
+}
+
+// quantScales are scales given by network file, zero scale is chosen by quantize.
+type quantScales struct {
+	hidden, output int32
+}
+
+// quantizedHidden is hidden layer quantized with scale.
+// Bounds are the largest absolute values of accumulator before scaling.
+type quantizedHidden struct {
+	weights, biases []int16
+	scale           int32
+	bounds          []float64
+}
+
+// quantizeHidden chooses the largest scale that can not overflow:
+// accumulator is bounded by bias and the largest weights of 32 pieces.
+// Given scale must not exceed this scale.
+func quantizeHidden(hiddenSize int, hiddenWeights, hiddenBiases []float32, scale int32) (quantizedHidden, error) {
+	if hiddenSize%16 != 0 {
+		return quantizedHidden{}, fmt.Errorf("hidden size %v is not a multiple of 16", hiddenSize)
+	}
+	var inputSize = len(hiddenWeights) / hiddenSize
+	var bounds = make([]float64, hiddenSize)
+	var maxBound float64
+	var column = make([]float64, inputSize)
+	for j := 0; j < hiddenSize; j++ {
+		for i := range column {
+			column[i] = math.Abs(float64(hiddenWeights[i*hiddenSize+j]))
+		}
+		sort.Sort(sort.Reverse(sort.Float64Slice(column)))
+		var bound = math.Abs(float64(hiddenBiases[j]))
+		for _, x := range column[:Min(maxActiveInputs, len(column))] {
+			bound += x
+		}
+		bounds[j] = bound
+		maxBound = math.Max(maxBound, bound)
+	}
+	// rounding adds at most 0.5 to every term
+	var hiddenScale = math.Floor((math.MaxInt16 - maxActiveInputs) / maxBound)
+	if hiddenScale < 1 {
+		return quantizedHidden{}, fmt.Errorf("hidden layer can not be quantized, accumulator bound %v", maxBound)
+	}
+	if scale != 0 {
+		if float64(scale) > hiddenScale {
+			return quantizedHidden{}, fmt.Errorf("hidden quantization scale %v exceeds safe scale %v", scale, hiddenScale)
+		}
+		hiddenScale = float64(scale)
+	}
+	return quantizedHidden{
+		weights: quantizeSlice(hiddenWeights, hiddenScale),
+		biases:  quantizeSlice(hiddenBiases, hiddenScale),
+		scale:   int32(hiddenScale),
+		bounds:  bounds,
+	}, nil
+}
+
+// quantize chooses the largest scales that can not overflow:
+// output sum is bounded by accumulator bounds of quantizeHidden.
+// Given scales must not exceed these scales.
+func quantize(
+	hiddenSize, outputBuckets int,
+	hiddenWeights, hiddenBiases, outputWeights, outputBiases []float32,
+	scales quantScales,
+) (*QuantizedWeights, error) {
+	var hidden, err = quantizeHidden(hiddenSize, hiddenWeights, hiddenBiases, scales.hidden)
+	if err != nil {
+		return nil, err
+	}
+	var hiddenScale, bounds = float64(hidden.scale), hidden.bounds
+
+	var outputSize = len(outputWeights) / outputBuckets
+	var outputBound, maxOutputWeight float64
+	for bucket, bias := range outputBiases {
+		var bound = hiddenScale * math.Abs(float64(bias))
+		for j, x := range outputWeights[bucket*outputSize : (bucket+1)*outputSize] {
+			var weight = math.Abs(float64(x))
+			bound += (hiddenScale*bounds[j%hiddenSize] + maxActiveInputs) * (weight + 1)
+			maxOutputWeight = math.Max(maxOutputWeight, weight)
+		}
+		outputBound = math.Max(outputBound, bound)
+	}
+	var outputScale = math.Floor(math.Min(math.MaxInt32/outputBound, math.MaxInt16/maxOutputWeight))
+	if outputScale < 1 {
+		return nil, fmt.Errorf("output layer can not be quantized, output bound %v", outputBound)
+	}
+	if scales.output != 0 {
+		if float64(scales.output) > outputScale {
+			return nil, fmt.Errorf("output quantization scale %v exceeds safe scale %v", scales.output, outputScale)
+		}
+		outputScale = float64(scales.output)
+	}
+
+	var quantizedBiases = make([]int32, outputBuckets)
+	for i, x := range outputBiases {
+		quantizedBiases[i] = int32(math.Round(float64(x) * hiddenScale * outputScale))
+	}
+	return &QuantizedWeights{
+		HiddenSize:    hiddenSize,
+		OutputBuckets: outputBuckets,
+		HiddenWeights: hidden.weights,
+		HiddenBiases:  hidden.biases,
+		OutputWeights: quantizeSlice(outputWeights, outputScale),
+		OutputBiases:  quantizedBiases,
+		HiddenScale:   int32(hiddenScale),
+		OutputScale:   int32(outputScale),
+	}, nil
+}
+
+func quantizeSlice(data []float32, scale float64) []int16 {
+	var result = make([]int16, len(data))
+	for i, x := range data {
+		result[i] = int16(math.Round(float64(x) * scale))
+	}
+	return result
+}
+
+// hiddenRow returns weights of input.
+func (w *QuantizedWeights) hiddenRow(input int) []int16 {
+	var index = input * w.HiddenSize
+	return w.HiddenWeights[index : index+w.HiddenSize]
+}
+
+// outputBucket selects output weights by number of pieces.
+func (w *QuantizedWeights) outputBucket(p *Position) (int, []int16) {
+	var bucket = OutputBucket(PopCount(p.AllPieces()), w.OutputBuckets)
+	if w.Dense != nil {
+		return bucket, nil
+	}
+	var size = len(w.OutputWeights) / w.OutputBuckets
+	return bucket, w.OutputWeights[bucket*size : (bucket+1)*size]
+}
+
+// output converts dot product of hidden layer to network output.
+func (w *QuantizedWeights) output(dot int32, bucket int) int {
+	return int((dot + w.OutputBiases[bucket]) / (w.HiddenScale * w.OutputScale))
+}
+
+// QuantizedEvaluationService keeps accumulator of every ply.
+// Accumulator is updated by moves lazily, when position is evaluated.
+type QuantizedEvaluationService struct {
+	*QuantizedWeights
+	updates      [MaxHeight]Updates
+	computed     [MaxHeight]bool
+	accumulators [MaxHeight][]int16
+	current      int
+	dense        denseBuffers
+}
+
+func NewQuantizedEvaluationService(weights *QuantizedWeights) *QuantizedEvaluationService {
+	var es = &QuantizedEvaluationService{}
+	es.QuantizedWeights = weights
+	for i := range es.accumulators {
+		es.accumulators[i] = make([]int16, weights.HiddenSize)
+	}
+	if weights.Dense != nil {
+		es.dense = weights.Dense.newBuffers(weights.HiddenSize)
+	}
+	return es
+}
+
+func (e *QuantizedEvaluationService) EvaluateQuick(p *Position) int {
+	e.computeAccumulator()
+	var accumulator = e.accumulators[e.current]
+	var bucket, outputWeights = e.outputBucket(p)
+	var output int
+	if e.Dense != nil {
+		e.Dense.setInput(e.dense[0], accumulator, e.HiddenScale)
+		output = int(e.Dense.output(e.dense, bucket))
+	} else {
+		output = e.output(dotRelu16(accumulator, outputWeights), bucket)
+	}
+	if !p.WhiteMove {
+		output = -output
+	}
+	return scaleOutput(p, output)
+}
+
+func (e *QuantizedEvaluationService) Evaluate(p *Position) int {
+	e.Init(p)
+	return e.EvaluateQuick(p)
+}
+
+func (e *QuantizedEvaluationService) Init(p *Position) {
+	e.current = 0
+	e.computed[e.current] = true
+	var accumulator = e.accumulators[e.current]
+	copy(accumulator, e.HiddenBiases)
+	for sq := 0; sq < 64; sq++ {
+		piece, side := p.GetPieceTypeAndSide(sq)
+		if piece != Empty {
+			var input = int(calculateNetInputIndex(side, piece, sq))
+			addWeights16(accumulator, accumulator, e.hiddenRow(input))
+		}
+	}
+}
+
+func (e *QuantizedEvaluationService) MakeMove(p *Position, m Move) {
+	e.current++
+	e.updates[e.current].fromMove(p, m)
+	e.computed[e.current] = false
+}
+
+// computeAccumulator applies updates of moves after the last computed accumulator.
+func (e *QuantizedEvaluationService) computeAccumulator() {
+	var last = e.current
+	for !e.computed[last] {
+		last--
+	}
+	for ply := last + 1; ply <= e.current; ply++ {
+		e.applyUpdates(e.accumulators[ply], e.accumulators[ply-1], &e.updates[ply], true, perspectiveView{})
+		e.computed[ply] = true
+	}
+}
+
+// applyUpdates computes dst from src by inputs of side with view.
+// Absolute network is white side with empty view.
+func (w *QuantizedWeights) applyUpdates(dst, src []int16, updates *Updates, side bool, view perspectiveView) {
+	if updates.Size == 0 {
+		copy(dst, src)
+		return
+	}
+	for i := 0; i < updates.Size; i++ {
+		var weights = w.hiddenRow(view.inputIndex(side, int(updates.Indices[i])))
+		if updates.Coeffs[i] == Add {
+			addWeights16(dst, src, weights)
+		} else {
+			subWeights16(dst, src, weights)
+		}
+		src = dst
+	}
+}
+
+func (e *QuantizedEvaluationService) UnmakeMove() {
+	e.current--
+}
+
+func (e *QuantizedEvaluationService) EvaluateProb(p *Position) float64 {
+	return evaluateProb(p, e.Evaluate(p))
+}
+
+func addWeights16Generic(dst, src, weights []int16) {
+	for i := range dst {
+		dst[i] = src[i] + weights[i]
+	}
+}
+
+func subWeights16Generic(dst, src, weights []int16) {
+	for i := range dst {
+		dst[i] = src[i] - weights[i]
+	}
+}
+
+// dotRelu16Generic is dot product of ReLU(accumulator) and weights.
+func dotRelu16Generic(accumulator, weights []int16) int32 {
+	var sum int32
+	for i, x := range accumulator {
+		if x > 0 {
+			sum += int32(x) * int32(weights[i])
+		}
+	}
+	return sum
+}
@@ -0,0 +1,27 @@
+//go:build avx
+// +build avx
+
+package eval
+
+// AVX2 kernels process 16 values at once, hidden size is a multiple of 16.
+
+//go:noescape
+func _add_weights16(dst, src, weights *int16, n int)
+
+//go:noescape
+func _sub_weights16(dst, src, weights *int16, n int)
+
+//go:noescape
+func _dot_relu16(accumulator, weights *int16, n int) int32
+
+func addWeights16(dst, src, weights []int16) {
+	_add_weights16(&dst[0], &src[0], &weights[0], len(dst))
+}
+
+func subWeights16(dst, src, weights []int16) {
+	_sub_weights16(&dst[0], &src[0], &weights[0], len(dst))
+}
+
+func dotRelu16(accumulator, weights []int16) int32 {
+	return _dot_relu16(&accumulator[0], &weights[0], len(accumulator))
+}
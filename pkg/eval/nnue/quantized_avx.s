@@ -0,0 +1,74 @@
+//go:build avx
+// +build avx
+
+#include "textflag.h"
+
+// func _add_weights16(dst, src, weights *int16, n int)
+TEXT ·_add_weights16(SB), NOSPLIT, $0-32
+	MOVQ dst+0(FP), DI
+	MOVQ src+8(FP), SI
+	MOVQ weights+16(FP), DX
+	MOVQ n+24(FP), CX
+	SHRQ $4, CX
+
+add_loop:
+	VMOVDQU (SI), Y0
+	VPADDW  (DX), Y0, Y0
+	VMOVDQU Y0, (DI)
+	ADDQ    $32, SI
+	ADDQ    $32, DX
+	ADDQ    $32, DI
+	DECQ    CX
+	JNZ     add_loop
+	VZEROUPPER
+	RET
+
+// func _sub_weights16(dst, src, weights *int16, n int)
+TEXT ·_sub_weights16(SB), NOSPLIT, $0-32
+	MOVQ dst+0(FP), DI
+	MOVQ src+8(FP), SI
+	MOVQ weights+16(FP), DX
+	MOVQ n+24(FP), CX
+	SHRQ $4, CX
+
+sub_loop:
+	VMOVDQU (SI), Y0
+	VPSUBW  (DX), Y0, Y0
+	VMOVDQU Y0, (DI)
+	ADDQ    $32, SI
+	ADDQ    $32, DX
+	ADDQ    $32, DI
+	DECQ    CX
+	JNZ     sub_loop
+	VZEROUPPER
+	RET
+
+// func _dot_relu16(accumulator, weights *int16, n int) int32
+TEXT ·_dot_relu16(SB), NOSPLIT, $0-28
+	MOVQ accumulator+0(FP), SI
+	MOVQ weights+8(FP), DX
+	MOVQ n+16(FP), CX
+	SHRQ $4, CX
+	VPXOR Y1, Y1, Y1
+	VPXOR Y2, Y2, Y2
+
+dot_loop:
+	VMOVDQU  (SI), Y0
+	VPMAXSW  Y1, Y0, Y0
+	VPMADDWD (DX), Y0, Y0
+	VPADDD   Y0, Y2, Y2
+	ADDQ     $32, SI
+	ADDQ     $32, DX
+	DECQ     CX
+	JNZ      dot_loop
+
+	VEXTRACTI128 $1, Y2, X3
+	VPADDD       X3, X2, X2
+	VPSHUFD      $0x4E, X2, X3
+	VPADDD       X3, X2, X2
+	VPSHUFD      $0xB1, X2, X3
+	VPADDD       X3, X2, X2
+	VMOVD        X2, AX
+	VZEROUPPER
+	MOVL         AX, ret+24(FP)
+	RET
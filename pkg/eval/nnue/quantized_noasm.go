@@ -0,0 +1,16 @@
+//go:build !avx
+// +build !avx
+
+package eval
+
+func addWeights16(dst, src, weights []int16) {
+	addWeights16Generic(dst, src, weights)
+}
+
+func subWeights16(dst, src, weights []int16) {
+	subWeights16Generic(dst, src, weights)
+}
+
+func dotRelu16(accumulator, weights []int16) int32 {
+	return dotRelu16Generic(accumulator, weights)
+}
@@ -0,0 +1,185 @@
+package eval
+
+import (
+	"math/rand"
+	"testing"
+
+	. "github.com/ChizhovVadim/CounterGo/pkg/common"
+)
+
+func TestQuantizedEvaluation(t *testing.T) {
+	net, err := LoadNetFile("n-30-5268.nn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	weights, err := net.Weights()
+	if err != nil {
+		t.Fatal(err)
+	}
+	quantizedWeights, err := QuantizeWeights(weights)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var floatEval = NewEvaluationService(weights)
+	var quantizedEval = NewQuantizedEvaluationService(quantizedWeights)
+
+	const tolerance = 12
+	checkTestPositions(t, NewQuantizedEvaluationService(quantizedWeights).Evaluate, floatEval.Evaluate, tolerance)
+	var maxDiff int
+	for _, line := range testGames(rand.New(rand.NewSource(1)), 50, 80) {
+		quantizedEval.Init(&line[0])
+		for i := range line {
+			if i > 0 {
+				quantizedEval.MakeMove(&line[i-1], line[i].LastMove)
+			}
+			var p = &line[i]
+			var incremental = quantizedEval.EvaluateQuick(p)
+			var quantized = NewQuantizedEvaluationService(quantizedWeights).Evaluate(p)
+			if incremental != quantized {
+				t.Fatalf("%v: incremental %v, full %v", p.String(), incremental, quantized)
+			}
+			var diff = AbsDelta(quantized, floatEval.Evaluate(p))
+			if diff > tolerance {
+				t.Fatalf("%v: quantized %v, float %v", p.String(), quantized, floatEval.Evaluate(p))
+			}
+			maxDiff = Max(maxDiff, diff)
+		}
+	}
+	t.Log("max difference", maxDiff)
+}
+
+func TestQuantizedOutputBuckets(t *testing.T) {
+	var rnd = rand.New(rand.NewSource(1))
+	var weights = NewWeights(256, 8)
+	for _, data := range [][]float32{weights.HiddenWeights, weights.HiddenBiases} {
+		for i := range data {
+			data[i] = float32(rnd.Float64() - 0.5)
+		}
+	}
+	for i := range weights.OutputWeights {
+		weights.OutputWeights[i] = float32(100 * (rnd.Float64() - 0.5))
+	}
+	for i := range weights.OutputBiases {
+		weights.OutputBiases[i] = float32(10 * i)
+	}
+	quantizedWeights, err := QuantizeWeights(weights)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var floatEval = NewEvaluationService(weights)
+	var quantizedEval = NewQuantizedEvaluationService(quantizedWeights)
+
+	const tolerance = 4
+	checkTestPositions(t, NewQuantizedEvaluationService(quantizedWeights).Evaluate, floatEval.Evaluate, tolerance)
+	for _, line := range testGames(rand.New(rand.NewSource(2)), 30, 120) {
+		quantizedEval.Init(&line[0])
+		for i := range line {
+			if i > 0 {
+				quantizedEval.MakeMove(&line[i-1], line[i].LastMove)
+			}
+			var p = &line[i]
+			var incremental = quantizedEval.EvaluateQuick(p)
+			var float = floatEval.Evaluate(p)
+			if AbsDelta(incremental, float) > tolerance {
+				t.Fatalf("%v: quantized %v, float %v", p.String(), incremental, float)
+			}
+		}
+		checkLazyUpdates(t, rnd, quantizedEval, NewQuantizedEvaluationService(quantizedWeights).Evaluate, line)
+		checkLazyUpdates(t, rnd, NewEvaluationService(weights), floatEval.Evaluate, line)
+	}
+}
+
+// testPositions are checked before random games, so a failure names a known position.
+var testPositions = []struct {
+	name, fen string
+}{
+	{"initial", InitialPositionFen},
+	{"black to move", "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1"},
+	{"kiwipete", "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1"},
+	{"mate threat", "r1bqkb1r/pppp1ppp/2n2n2/4p2Q/2B1P3/8/PPPP1PPP/RNB1K1NR w KQkq - 4 4"},
+	{"wac 1", "2rr3k/pp3pp1/1nnqbN1p/3pN3/2pP4/2P3Q1/PPB4P/R4RK1 w - - 0 1"},
+	{"exposed king", "r1b1kb1r/pppp1ppp/5q2/4n3/3KP3/2N3PN/PPP4P/R1BQ1B1R b kq - 0 1"},
+	{"pawn endgame", "8/8/8/4k3/8/8/4P3/4K3 w - - 0 1"},
+	{"pawn race", "8/p7/8/8/8/8/7P/k6K w - - 0 1"},
+	{"lucena", "1K1k4/1P6/8/8/8/8/r7/2R5 w - - 0 1"},
+	{"extra rook", "8/5pk1/6p1/8/8/6P1/5PK1/3R4 w - - 0 1"},
+	{"bishop and knight", "8/8/8/8/8/2k5/8/KBN5 w - - 0 1"},
+	{"bare kings", "8/8/4k3/8/8/3K4/8/8 w - - 0 1"},
+	{"queen against rooks", "3rr1k1/5ppp/8/8/8/8/5PPP/3Q2K1 w - - 0 1"},
+	{"pawns only", "4k3/pppppppp/8/8/8/8/PPPPPPPP/4K3 w - - 0 1"},
+	{"four queens", "4k3/8/8/8/8/8/QQQQ4/4K3 w - - 0 1"},
+	{"four queens black", "qqqq4/4k3/8/8/8/8/8/4K3 b - - 0 1"},
+}
+
+func checkTestPositions(t *testing.T, quantized, float func(p *Position) int, tolerance int) {
+	for _, test := range testPositions {
+		var p, err = NewPositionFromFEN(test.fen)
+		if err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+		var quantizedScore, floatScore = quantized(&p), float(&p)
+		if AbsDelta(quantizedScore, floatScore) > tolerance {
+			t.Errorf("%v %v: quantized %v, float %v", test.name, test.fen, quantizedScore, floatScore)
+		}
+	}
+}
+
+type testEvaluator interface {
+	Init(p *Position)
+	MakeMove(p *Position, m Move)
+	UnmakeMove()
+	EvaluateQuick(p *Position) int
+	Evaluate(p *Position) int
+}
+
+// checkLazyUpdates evaluates positions of line at random plies forward and back.
+func checkLazyUpdates(t *testing.T, rnd *rand.Rand, eval testEvaluator, full func(p *Position) int, line []Position) {
+	var check = func(i int) {
+		var p = &line[i]
+		if rnd.Intn(3) != 0 {
+			return
+		}
+		var lazy, expected = eval.EvaluateQuick(p), full(p)
+		if lazy != expected {
+			t.Fatalf("%v: lazy %v, full %v", p.String(), lazy, expected)
+		}
+	}
+	eval.Init(&line[0])
+	for i := 1; i < len(line); i++ {
+		eval.MakeMove(&line[i-1], line[i].LastMove)
+		check(i)
+	}
+	for i := len(line) - 1; i > 0; i-- {
+		eval.UnmakeMove()
+		check(i - 1)
+	}
+}
+
+// testGames plays random games from the initial position.
+func testGames(rnd *rand.Rand, games, plies int) [][]Position {
+	var result [][]Position
+	var buf [MaxMoves]OrderedMove
+	for g := 0; g < games; g++ {
+		var start, _ = NewPositionFromFEN(InitialPositionFen)
+		var line = []Position{start}
+		for len(line) < plies {
+			var p = &line[len(line)-1]
+			var ml = p.GenerateMoves(buf[:])
+			rnd.Shuffle(len(ml), func(i, j int) { ml[i], ml[j] = ml[j], ml[i] })
+			var child Position
+			var found = false
+			for i := range ml {
+				if p.MakeMove(ml[i].Move, &child) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				break
+			}
+			line = append(line, child)
+		}
+		result = append(result, line)
+	}
+	return result
+}
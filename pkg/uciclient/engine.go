@@ -0,0 +1,292 @@
+package uciclient
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/ChizhovVadim/CounterGo/pkg/common"
+)
+
+const (
+	handshakeTimeout = 10 * time.Second
+	quitTimeout      = 3 * time.Second
+	stopTimeout      = 3 * time.Second
+)
+
+var errEngineExited = errors.New("uci engine exited")
+
+type Option struct {
+	Name  string
+	Value string
+}
+
+// Engine runs an external UCI engine as a subprocess.
+type Engine struct {
+	Path    string
+	Args    []string
+	Options []Option
+	name    string
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	lines   chan string
+	err     error
+}
+
+func NewEngine(path string, args []string, options []Option) *Engine {
+	return &Engine{
+		Path:    path,
+		Args:    args,
+		Options: options,
+	}
+}
+
+// Start launches the engine process, performs the uci handshake and sets options.
+func (e *Engine) Start() error {
+	var cmd = exec.Command(e.Path, e.Args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	err = cmd.Start()
+	if err != nil {
+		return err
+	}
+	e.cmd = cmd
+	e.stdin = stdin
+	e.lines = make(chan string, 64)
+	e.err = nil
+
+	go func(lines chan<- string) {
+		defer close(lines)
+		var scanner = bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}(e.lines)
+
+	err = e.handshake()
+	if err != nil {
+		e.fail(err)
+		e.Close()
+		return err
+	}
+	return nil
+}
+
+func (e *Engine) handshake() error {
+	var err = e.send("uci")
+	if err != nil {
+		return err
+	}
+	err = e.waitFor("uciok", handshakeTimeout, func(line string) {
+		if strings.HasPrefix(line, "id name ") {
+			e.name = strings.TrimSpace(strings.TrimPrefix(line, "id name "))
+		}
+	})
+	if err != nil {
+		return err
+	}
+	for _, option := range e.Options {
+		err = e.send(fmt.Sprintf("setoption name %v value %v", option.Name, option.Value))
+		if err != nil {
+			return err
+		}
+	}
+	return e.isReady()
+}
+
+// Name returns the name reported by the engine in the uci handshake.
+func (e *Engine) Name() string {
+	return e.name
+}
+
+// Err returns the error that made the engine unusable, if any.
+func (e *Engine) Err() error {
+	return e.err
+}
+
+func (e *Engine) Prepare() {
+	if e.err != nil {
+		return
+	}
+	var err = e.isReady()
+	if err != nil {
+		e.fail(err)
+	}
+}
+
+func (e *Engine) Clear() {
+	if e.err != nil {
+		return
+	}
+	var err = e.send("ucinewgame")
+	if err == nil {
+		err = e.isReady()
+	}
+	if err != nil {
+		e.fail(err)
+	}
+}
+
+// Search returns an empty SearchInfo if the engine failed. The reason is available in Err.
+// The engine is killed if it does not answer bestmove in time after the search is cancelled.
+func (e *Engine) Search(ctx context.Context, searchParams common.SearchParams) common.SearchInfo {
+	if e.err != nil {
+		return common.SearchInfo{}
+	}
+	var start = time.Now()
+	var p = &searchParams.Positions[len(searchParams.Positions)-1]
+
+	var err = e.send(positionCommand(searchParams.Positions))
+	if err == nil {
+		err = e.send(goCommand(searchParams.Limits))
+	}
+	if err != nil {
+		e.fail(err)
+		return common.SearchInfo{}
+	}
+
+	var result common.SearchInfo
+	var done = ctx.Done()
+	var stopped <-chan time.Time
+	for {
+		select {
+		case <-done:
+			done = nil
+			var err = e.send("stop")
+			if err != nil {
+				e.fail(err)
+				return common.SearchInfo{}
+			}
+			var timer = time.NewTimer(stopTimeout)
+			defer timer.Stop()
+			stopped = timer.C
+		case <-stopped:
+			e.fail(fmt.Errorf("uci engine %v: timeout waiting for bestmove after stop", e.Path))
+			e.cmd.Process.Kill()
+			e.Close()
+			return common.SearchInfo{}
+		case line, ok := <-e.lines:
+			if !ok {
+				e.fail(errEngineExited)
+				return common.SearchInfo{}
+			}
+			var fields = strings.Fields(line)
+			if len(fields) == 0 {
+				continue
+			}
+			switch fields[0] {
+			case "info":
+				var si, ok = parseInfo(p, fields[1:])
+				if !ok {
+					continue
+				}
+				if si.Time == 0 {
+					si.Time = time.Since(start)
+				}
+				result = si
+				if searchParams.Progress != nil {
+					searchParams.Progress(si)
+				}
+			case "bestmove":
+				if len(fields) < 2 {
+					e.fail(fmt.Errorf("bad bestmove %v", line))
+					return common.SearchInfo{}
+				}
+				var move = parseMove(p, fields[1])
+				if move == common.MoveEmpty {
+					e.fail(fmt.Errorf("bad bestmove %v", line))
+					return common.SearchInfo{}
+				}
+				if len(result.MainLine) == 0 || result.MainLine[0] != move {
+					result.MainLine = []common.Move{move}
+				}
+				result.Time = time.Since(start)
+				return result
+			}
+		}
+	}
+}
+
+// Close asks the engine to quit and kills it if it does not exit in time.
+func (e *Engine) Close() error {
+	if e.cmd == nil {
+		return nil
+	}
+	e.send("quit")
+	e.stdin.Close()
+	go func(lines <-chan string) {
+		for range lines {
+		}
+	}(e.lines)
+	var exited = make(chan error, 1)
+	go func() {
+		exited <- e.cmd.Wait()
+	}()
+	var err error
+	select {
+	case err = <-exited:
+	case <-time.After(quitTimeout):
+		e.cmd.Process.Kill()
+		err = <-exited
+	}
+	e.cmd = nil
+	if e.err == nil {
+		e.err = errEngineExited
+	}
+	return err
+}
+
+func (e *Engine) isReady() error {
+	var err = e.send("isready")
+	if err != nil {
+		return err
+	}
+	return e.waitFor("readyok", handshakeTimeout, nil)
+}
+
+func (e *Engine) send(command string) error {
+	if e.stdin == nil {
+		return errors.New("uci engine not started")
+	}
+	_, err := io.WriteString(e.stdin, command+"\n")
+	return err
+}
+
+func (e *Engine) waitFor(token string, timeout time.Duration, onLine func(line string)) error {
+	var timer = time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+			return fmt.Errorf("uci engine %v: timeout waiting for %v", e.Path, token)
+		case line, ok := <-e.lines:
+			if !ok {
+				return errEngineExited
+			}
+			if strings.TrimSpace(line) == token {
+				return nil
+			}
+			if onLine != nil {
+				onLine(line)
+			}
+		}
+	}
+}
+
+func (e *Engine) fail(err error) error {
+	if e.err == nil {
+		e.err = err
+	}
+	return err
+}
@@ -0,0 +1,148 @@
+package uciclient_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ChizhovVadim/CounterGo/internal/arena"
+	"github.com/ChizhovVadim/CounterGo/internal/tactic"
+	"github.com/ChizhovVadim/CounterGo/pkg/common"
+	"github.com/ChizhovVadim/CounterGo/pkg/engine"
+	counter "github.com/ChizhovVadim/CounterGo/pkg/eval/counter"
+	"github.com/ChizhovVadim/CounterGo/pkg/uci"
+	"github.com/ChizhovVadim/CounterGo/pkg/uciclient"
+)
+
+var (
+	_ arena.IEngine  = (*uciclient.Engine)(nil)
+	_ tactic.IEngine = (*uciclient.Engine)(nil)
+)
+
+const stubEngineEnv = "UCICLIENT_STUB_ENGINE"
+
+// The test binary doubles as a UCI engine: Counter with hand crafted eval,
+// or an engine that never answers go with stubEngineEnv "hang".
+func TestMain(m *testing.M) {
+	switch os.Getenv(stubEngineEnv) {
+	case "hang":
+		runHangingEngine()
+		os.Exit(0)
+	case "1":
+		var eng = engine.NewEngine(engine.NewMainOptions(func() interface{} {
+			return counter.NewEvaluationService()
+		}))
+		var protocol = uci.New("Stub", "test", "dev", eng,
+			[]uci.Option{
+				&uci.IntOption{Name: "Hash", Min: 4, Max: 1 << 16, Value: &eng.Options.Hash},
+			})
+		protocol.Run(log.New(os.Stderr, "", 0))
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func runHangingEngine() {
+	var scanner = bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		switch scanner.Text() {
+		case "uci":
+			fmt.Println("id name Hang")
+			fmt.Println("uciok")
+		case "isready":
+			fmt.Println("readyok")
+		}
+	}
+}
+
+func startStubEngine(t *testing.T) *uciclient.Engine {
+	return startEngine(t, "1")
+}
+
+func startEngine(t *testing.T, stub string) *uciclient.Engine {
+	t.Setenv(stubEngineEnv, stub)
+	var exe, err = os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var eng = uciclient.NewEngine(exe, nil, []uciclient.Option{{Name: "Hash", Value: "8"}})
+	err = eng.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { eng.Close() })
+	return eng
+}
+
+func TestSearch(t *testing.T) {
+	var eng = startStubEngine(t)
+	if eng.Name() != "Stub dev" {
+		t.Errorf("unexpected engine name %q", eng.Name())
+	}
+	eng.Clear()
+
+	var pos, _ = common.NewPositionFromFEN(common.InitialPositionFen)
+	var positions = []common.Position{pos}
+	for _, smove := range []string{"e2e4", "e7e5", "g1f3"} {
+		var child, ok = positions[len(positions)-1].MakeMoveLAN(smove)
+		if !ok {
+			t.Fatal(smove)
+		}
+		positions = append(positions, child)
+	}
+
+	var progress int
+	var si = eng.Search(context.Background(), common.SearchParams{
+		Positions: positions,
+		Limits:    common.LimitsType{Depth: 6},
+		Progress:  func(si common.SearchInfo) { progress++ },
+	})
+	if eng.Err() != nil {
+		t.Fatal(eng.Err())
+	}
+	if len(si.MainLine) == 0 {
+		t.Fatal("no best move")
+	}
+	var child common.Position
+	if !positions[len(positions)-1].MakeMove(si.MainLine[0], &child) {
+		t.Fatalf("illegal best move %v", si.MainLine[0])
+	}
+	if si.Depth != 6 || progress == 0 {
+		t.Errorf("unexpected search info %+v, progress %v", si, progress)
+	}
+}
+
+func TestSearchCancel(t *testing.T) {
+	var eng = startStubEngine(t)
+	var pos, _ = common.NewPositionFromFEN(common.InitialPositionFen)
+	var ctx, cancel = context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	var si = eng.Search(ctx, common.SearchParams{
+		Positions: []common.Position{pos},
+		Limits:    common.LimitsType{Infinite: true},
+	})
+	if eng.Err() != nil {
+		t.Fatal(eng.Err())
+	}
+	if len(si.MainLine) == 0 {
+		t.Fatal("no best move")
+	}
+}
+
+func TestSearchStopTimeout(t *testing.T) {
+	var eng = startEngine(t, "hang")
+	var pos, _ = common.NewPositionFromFEN(common.InitialPositionFen)
+	var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	var si = eng.Search(ctx, common.SearchParams{
+		Positions: []common.Position{pos},
+		Limits:    common.LimitsType{Infinite: true},
+	})
+	if eng.Err() == nil || len(si.MainLine) != 0 {
+		t.Fatalf("search of hanging engine returned %+v, error %v", si, eng.Err())
+	}
+}
@@ -0,0 +1,130 @@
+package uciclient
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ChizhovVadim/CounterGo/pkg/common"
+)
+
+func positionCommand(positions []common.Position) string {
+	var sb = &strings.Builder{}
+	fmt.Fprintf(sb, "position fen %v", positions[0].String())
+	if len(positions) > 1 {
+		sb.WriteString(" moves")
+		for i := 1; i < len(positions); i++ {
+			sb.WriteString(" ")
+			sb.WriteString(positions[i].LastMove.String())
+		}
+	}
+	return sb.String()
+}
+
+func goCommand(limits common.LimitsType) string {
+	var sb = &strings.Builder{}
+	sb.WriteString("go")
+	if limits.Infinite {
+		sb.WriteString(" infinite")
+	}
+	if limits.WhiteTime > 0 {
+		fmt.Fprintf(sb, " wtime %v", limits.WhiteTime)
+	}
+	if limits.BlackTime > 0 {
+		fmt.Fprintf(sb, " btime %v", limits.BlackTime)
+	}
+	if limits.WhiteIncrement > 0 {
+		fmt.Fprintf(sb, " winc %v", limits.WhiteIncrement)
+	}
+	if limits.BlackIncrement > 0 {
+		fmt.Fprintf(sb, " binc %v", limits.BlackIncrement)
+	}
+	if limits.MovesToGo > 0 {
+		fmt.Fprintf(sb, " movestogo %v", limits.MovesToGo)
+	}
+	if limits.MoveTime > 0 {
+		fmt.Fprintf(sb, " movetime %v", limits.MoveTime)
+	}
+	if limits.Depth > 0 {
+		fmt.Fprintf(sb, " depth %v", limits.Depth)
+	}
+	if limits.Nodes > 0 {
+		fmt.Fprintf(sb, " nodes %v", limits.Nodes)
+	}
+	if limits.Mate > 0 {
+		fmt.Fprintf(sb, " mate %v", limits.Mate)
+	}
+	return sb.String()
+}
+
+// parseInfo accepts only info lines that carry a score and a principal variation.
+func parseInfo(p *common.Position, fields []string) (common.SearchInfo, bool) {
+	var result common.SearchInfo
+	var hasScore bool
+	for i := 0; i < len(fields); i++ {
+		switch fields[i] {
+		case "depth":
+			if i+1 < len(fields) {
+				result.Depth, _ = strconv.Atoi(fields[i+1])
+				i++
+			}
+		case "nodes":
+			if i+1 < len(fields) {
+				result.Nodes, _ = strconv.ParseInt(fields[i+1], 10, 64)
+				i++
+			}
+		case "time":
+			if i+1 < len(fields) {
+				var ms, _ = strconv.Atoi(fields[i+1])
+				result.Time = time.Duration(ms) * time.Millisecond
+				i++
+			}
+		case "score":
+			if i+2 < len(fields) {
+				var v, err = strconv.Atoi(fields[i+2])
+				if err == nil {
+					if fields[i+1] == "mate" {
+						result.Score = common.UciScore{Mate: v}
+						hasScore = true
+					} else if fields[i+1] == "cp" {
+						result.Score = common.UciScore{Centipawns: v}
+						hasScore = true
+					}
+				}
+				i += 2
+			}
+		case "pv":
+			result.MainLine = parseLine(p, fields[i+1:])
+			i = len(fields)
+		case "string":
+			return common.SearchInfo{}, false
+		}
+	}
+	if !hasScore || len(result.MainLine) == 0 {
+		return common.SearchInfo{}, false
+	}
+	return result, true
+}
+
+func parseLine(p *common.Position, smoves []string) []common.Move {
+	var result []common.Move
+	var pos = *p
+	for _, smove := range smoves {
+		var child, ok = pos.MakeMoveLAN(smove)
+		if !ok {
+			break
+		}
+		result = append(result, child.LastMove)
+		pos = child
+	}
+	return result
+}
+
+func parseMove(p *common.Position, smove string) common.Move {
+	var child, ok = p.MakeMoveLAN(smove)
+	if !ok {
+		return common.MoveEmpty
+	}
+	return child.LastMove
+}